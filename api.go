@@ -0,0 +1,342 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/shurcooL/github_flavored_markdown"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+)
+
+const (
+	apiPerPage    = 20
+	apiMaxPerPage = 100
+)
+
+type apiMemo struct {
+	Name       string                 `json:"name"`
+	Notebook   string                 `json:"notebook,omitempty"`
+	Title      string                 `json:"title"`
+	Date       *time.Time             `json:"date,omitempty"`
+	Tags       []string               `json:"tags"`
+	Categories []string               `json:"categories"`
+	Extra      map[string]interface{} `json:"extra,omitempty"`
+	ModTime    time.Time              `json:"mtime"`
+	Body       *string                `json:"body,omitempty"`
+	HTML       *string                `json:"html,omitempty"`
+}
+
+type apiMemoRequest struct {
+	Title    string   `json:"title"`
+	Notebook string   `json:"notebook"`
+	Tags     []string `json:"tags"`
+	Body     *string  `json:"body"`
+}
+
+func (srv *server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/memos", srv.apiListMemos)
+	mux.HandleFunc("POST /api/v1/memos", srv.apiCreateMemo)
+	mux.HandleFunc("GET /api/v1/memos/{name...}", srv.apiGetMemo)
+	mux.HandleFunc("PUT /api/v1/memos/{name...}", srv.apiUpdateMemo)
+	mux.HandleFunc("DELETE /api/v1/memos/{name...}", srv.apiDeleteMemo)
+	mux.HandleFunc("GET /api/v1/search", srv.apiSearch)
+	mux.HandleFunc("GET /api/v1/tags", srv.apiTags)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var qerr *query.Error
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, fs.ErrExist):
+		status = http.StatusConflict
+	case errors.Is(err, errConflict):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, fs.ErrInvalid), errors.As(err, &qerr):
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// isJSON returns true if the content type of the request is JSON. Other
+// requests are rejected, since forms can be posted from the other web pages
+// without the preflight.
+func isJSON(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("content-type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func boolParam(v url.Values, key string) bool {
+	b, _ := strconv.ParseBool(v.Get(key))
+	return b
+}
+
+// pageParams returns the page and the number of items per page.
+func pageParams(v url.Values) (int, int) {
+	page, err := strconv.Atoi(v.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(v.Get("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = apiPerPage
+	}
+	if perPage > apiMaxPerPage {
+		perPage = apiMaxPerPage
+	}
+	return page, perPage
+}
+
+// paginate returns the range of the items in the page.
+func paginate(total, page, perPage int) (int, int) {
+	// (page-1)*perPage can overflow with the large page.
+	if page-1 > total/perPage {
+		return total, total
+	}
+	start := (page - 1) * perPage
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+func newAPIMemo(doc *query.Doc, body []byte, withBody, withHTML bool) *apiMemo {
+	m := &apiMemo{
+		Name:       doc.Name,
+		Notebook:   store.Notebook(doc.Name),
+		Title:      doc.Meta.Title,
+		Tags:       doc.Meta.Tags,
+		Categories: doc.Meta.Categories,
+		Extra:      doc.Meta.Extra,
+		ModTime:    doc.ModTime,
+	}
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	if m.Categories == nil {
+		m.Categories = []string{}
+	}
+	if len(m.Extra) == 0 {
+		m.Extra = nil
+	}
+	if !doc.Meta.Date.IsZero() {
+		m.Date = &doc.Meta.Date
+	}
+	if withBody {
+		s := string(body)
+		m.Body = &s
+	}
+	if withHTML {
+		s := string(github_flavored_markdown.Markdown(doc.Content))
+		m.HTML = &s
+	}
+	return m
+}
+
+func (srv *server) apiListMemos(w http.ResponseWriter, req *http.Request) {
+	v := req.URL.Query()
+	q, err := query.Parse(v.Get("q"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	docs, err := findMemos(srv.s, q)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	var filtered []*query.Doc
+	for _, doc := range docs {
+		if hasTags(doc.Meta, v["tag"]) {
+			filtered = append(filtered, doc)
+		}
+	}
+
+	page, perPage := pageParams(v)
+	start, end := paginate(len(filtered), page, perPage)
+	withBody, withHTML := boolParam(v, "body"), boolParam(v, "html")
+	memos := []*apiMemo{}
+	for _, doc := range filtered[start:end] {
+		var body []byte
+		if withBody {
+			memo, err := srv.s.Get(doc.Name)
+			if err != nil {
+				writeAPIError(w, err)
+				return
+			}
+			body = memo.Body
+		}
+		memos = append(memos, newAPIMemo(doc, body, withBody, withHTML))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"memos":    memos,
+		"page":     page,
+		"per_page": perPage,
+		"total":    len(filtered),
+	})
+}
+
+func (srv *server) apiGetMemo(w http.ResponseWriter, req *http.Request) {
+	memo, err := srv.s.Get(req.PathValue("name"))
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("etag", memoETag(memo))
+	writeJSON(w, http.StatusOK, newAPIMemo(query.NewDoc(memo), memo.Body, true, boolParam(req.URL.Query(), "html")))
+}
+
+func (srv *server) apiCreateMemo(w http.ResponseWriter, req *http.Request) {
+	if !isJSON(req) {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "content-type must be application/json"})
+		return
+	}
+	var r apiMemoRequest
+	if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var body []byte
+	if r.Body != nil {
+		body = []byte(*r.Body)
+	}
+	name, err := srv.createMemo(r.Title, r.Notebook, r.Tags, body)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	memo, err := srv.s.Get(name)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("location", "/api/v1/memos/"+name)
+	w.Header().Set("etag", memoETag(memo))
+	writeJSON(w, http.StatusCreated, newAPIMemo(query.NewDoc(memo), memo.Body, true, boolParam(req.URL.Query(), "html")))
+}
+
+func (srv *server) apiUpdateMemo(w http.ResponseWriter, req *http.Request) {
+	if !isJSON(req) {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "content-type must be application/json"})
+		return
+	}
+	var r apiMemoRequest
+	if err := json.NewDecoder(req.Body).Decode(&r); err != nil || r.Body == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body required"})
+		return
+	}
+	name := req.PathValue("name")
+	if err := srv.updateMemo(name, req.Header.Get("if-match"), []byte(*r.Body)); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	memo, err := srv.s.Get(name)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("etag", memoETag(memo))
+	writeJSON(w, http.StatusOK, newAPIMemo(query.NewDoc(memo), memo.Body, true, boolParam(req.URL.Query(), "html")))
+}
+
+func (srv *server) apiDeleteMemo(w http.ResponseWriter, req *http.Request) {
+	name := req.PathValue("name")
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var err error
+	if boolParam(req.URL.Query(), "permanent") {
+		err = srv.s.Delete(name)
+	} else {
+		err = srv.cfg.moveToTrash(srv.s, name)
+	}
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	srv.autoCommit("delete: "+name, name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (srv *server) apiSearch(w http.ResponseWriter, req *http.Request) {
+	v := req.URL.Query()
+	q, err := query.Parse(v.Get("query"))
+	if err == nil {
+		err = checkQuery(q, srv.s)
+	}
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if err := srv.cfg.updateIndex(srv.idx, srv.s); err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	type result struct {
+		*apiMemo
+		Score float64 `json:"score"`
+	}
+	var matched []*result
+	for _, r := range srv.idx.Search(v.Get("q")) {
+		memo, err := srv.s.Get(r.Name)
+		if err != nil {
+			continue
+		}
+		doc := query.NewDoc(memo)
+		if !q.Match(doc) {
+			continue
+		}
+		matched = append(matched, &result{apiMemo: newAPIMemo(doc, nil, false, boolParam(v, "html")), Score: r.Score})
+	}
+
+	page, perPage := pageParams(v)
+	start, end := paginate(len(matched), page, perPage)
+	results := matched[start:end]
+	if results == nil {
+		results = []*result{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results":  results,
+		"page":     page,
+		"per_page": perPage,
+		"total":    len(matched),
+	})
+}
+
+func (srv *server) apiTags(w http.ResponseWriter, req *http.Request) {
+	counts, err := tagCounts(srv.s)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	type tag struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	tags := []tag{}
+	for name, count := range counts {
+		tags = append(tags, tag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	writeJSON(w, http.StatusOK, tags)
+}
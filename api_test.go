@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/store"
+)
+
+func newTestServer(t *testing.T, s store.Store) *httptest.Server {
+	t.Helper()
+
+	setupTestHome(t)
+	if err := os.MkdirAll(configDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	srv := &server{cfg: &config{MemoDir: t.TempDir()}, s: s, idx: index.New()}
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func doJSON(t *testing.T, method, url, body string, status int, v interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("content-type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s: want status %d but got %d", method, url, status, resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAPI(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntags: [work]\n---\n# Foo\nhello\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\nhello world\n"))
+	s.Create("work/2017-01-03-baz.md", []byte("# Baz\n"))
+	ts := newTestServer(t, s)
+
+	var list struct {
+		Memos []*apiMemo `json:"memos"`
+		Total int        `json:"total"`
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/memos?per_page=2&page=2", "", http.StatusOK, &list)
+	if list.Total != 3 || len(list.Memos) != 1 || list.Memos[0].Name != "2017-01-01-foo.md" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/memos?page=9223372036854775807", "", http.StatusOK, &list)
+	if len(list.Memos) != 0 || list.Total != 3 {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/memos?q=notebook:work", "", http.StatusOK, &list)
+	if list.Total != 1 || list.Memos[0].Notebook != "work" || list.Memos[0].Body != nil {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/memos?q=tag:", "", http.StatusBadRequest, nil)
+	doJSON(t, "GET", ts.URL+"/api/v1/search?q=hello&query=tga:work", "", http.StatusBadRequest, nil)
+
+	var memo apiMemo
+	doJSON(t, "GET", ts.URL+"/api/v1/memos/2017-01-01-foo.md?html=true", "", http.StatusOK, &memo)
+	if memo.Title != "Foo" || len(memo.Tags) != 1 || memo.Body == nil || memo.HTML == nil || !strings.Contains(*memo.HTML, "<p>hello</p>") {
+		t.Fatalf("unexpected memo: %+v", memo)
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/memos/missing.md", "", http.StatusNotFound, nil)
+
+	doJSON(t, "POST", ts.URL+"/api/v1/memos", `{"title":"new memo","notebook":"work","tags":["a"]}`, http.StatusCreated, &memo)
+	if !strings.HasPrefix(memo.Notebook+"/", "work/") || !strings.HasSuffix(memo.Name, "-new-memo.md") || memo.Tags[0] != "a" {
+		t.Fatalf("unexpected memo: %+v", memo)
+	}
+	doJSON(t, "PUT", ts.URL+"/api/v1/memos/"+memo.Name, `{"body":"# renamed\n"}`, http.StatusOK, &memo)
+	if memo.Title != "renamed" {
+		t.Fatalf("unexpected memo: %+v", memo)
+	}
+
+	// forms posted from the other web pages are rejected
+	for _, method := range []string{"POST", "PUT"} {
+		path := "/api/v1/memos"
+		if method == "PUT" {
+			path += "/" + memo.Name
+		}
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(`{"title":"csrf","body":"x"}`))
+		req.Header.Set("content-type", "text/plain")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Fatalf("%s %s: want status %d but got %d", method, path, http.StatusUnsupportedMediaType, resp.StatusCode)
+		}
+	}
+
+	doJSON(t, "DELETE", ts.URL+"/api/v1/memos/"+memo.Name+"?permanent=true", "", http.StatusNoContent, nil)
+	doJSON(t, "DELETE", ts.URL+"/api/v1/memos/"+memo.Name, "", http.StatusNotFound, nil)
+
+	var search struct {
+		Results []struct {
+			Name  string  `json:"name"`
+			Score float64 `json:"score"`
+		} `json:"results"`
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/search?q=hello&query=-tag:work", "", http.StatusOK, &search)
+	if len(search.Results) != 1 || search.Results[0].Name != "2017-01-02-bar.md" || search.Results[0].Score <= 0 {
+		t.Fatalf("unexpected results: %+v", search)
+	}
+
+	var tags []struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	doJSON(t, "GET", ts.URL+"/api/v1/tags", "", http.StatusOK, &tags)
+	if len(tags) != 1 || tags[0].Name != "work" || tags[0].Count != 1 {
+		t.Fatalf("unexpected tags: %+v", tags)
+	}
+}
+
+func TestAPIHiddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, ".git", "config")
+	if err := os.MkdirAll(filepath.Dir(config), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte("[core]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, store.NewFS(dir))
+
+	url := ts.URL + "/api/v1/memos/.git/config"
+	doJSON(t, "GET", url, "", http.StatusBadRequest, nil)
+	doJSON(t, "PUT", url, `{"body":"[core]\nfsmonitor = touch pwned\n"}`, http.StatusBadRequest, nil)
+	doJSON(t, "DELETE", url, "", http.StatusBadRequest, nil)
+	doJSON(t, "DELETE", url+"?permanent=true", "", http.StatusBadRequest, nil)
+	b, err := os.ReadFile(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "[core]\n" {
+		t.Fatalf("want .git/config unchanged but got %q", b)
+	}
+}
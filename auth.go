@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// serveConfig is the configuration for serve command.
+type serveConfig struct {
+	Users    map[string]string `toml:"users,omitempty"` // user name to bcrypt hash
+	Token    string            `toml:"token,omitempty"`
+	ReadOnly bool              `toml:"readonly,omitempty"`
+	TLSCert  string            `toml:"tlscert,omitempty"`
+	TLSKey   string            `toml:"tlskey,omitempty"`
+}
+
+func (sc *serveConfig) authEnabled() bool {
+	return len(sc.Users) > 0 || sc.Token != ""
+}
+
+// authorized returns true if the request has the valid credential.
+func (sc *serveConfig) authorized(req *http.Request) bool {
+	if !sc.authEnabled() {
+		return true
+	}
+	if sc.Token != "" {
+		if auth := req.Header.Get("authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token := strings.TrimPrefix(auth, "Bearer ")
+			return subtle.ConstantTimeCompare([]byte(token), []byte(sc.Token)) == 1
+		}
+	}
+	user, pass, ok := req.BasicAuth()
+	if !ok {
+		return false
+	}
+	hash, ok := sc.Users[user]
+	if !ok {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) == nil
+}
+
+// isWrite returns true if the request modifies memos.
+func isWrite(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return true
+	}
+	return req.URL.Path == "/new" || strings.HasPrefix(req.URL.Path, "/edit/")
+}
+
+// isLocalHost returns true if the Host header is localhost or the loopback
+// address, with or without the port.
+func isLocalHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	switch host {
+	case "localhost", "127.0.0.1", "::1", "[::1]":
+		return true
+	}
+	return false
+}
+
+// guard wraps the handler with the authentication and read-only mode.
+func (srv *server) guard(h http.Handler) http.Handler {
+	sc := &srv.cfg.Serve
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if srv.localOnly && !isLocalHost(req.Host) {
+			http.Error(w, "host not allowed", http.StatusForbidden)
+			return
+		}
+		if !sc.authorized(req) {
+			if len(sc.Users) > 0 {
+				w.Header().Set("www-authenticate", `Basic realm="memo"`)
+			} else {
+				w.Header().Set("www-authenticate", `Bearer realm="memo"`)
+			}
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		if sc.ReadOnly && isWrite(req) {
+			http.Error(w, "memo server is read-only", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/store"
+)
+
+func TestGuard(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	srv := &server{cfg: &config{Serve: serveConfig{
+		Users:    map[string]string{"mattn": string(hash)},
+		Token:    "token",
+		ReadOnly: true,
+	}}, s: s, idx: index.New()}
+	h := srv.handler()
+
+	tests := []struct {
+		method string
+		path   string
+		auth   func(req *http.Request)
+		status int
+	}{
+		{"GET", "/api/v1/memos", nil, http.StatusUnauthorized},
+		{"GET", "/api/v1/memos", func(req *http.Request) { req.SetBasicAuth("mattn", "wrong") }, http.StatusUnauthorized},
+		{"GET", "/api/v1/memos", func(req *http.Request) { req.SetBasicAuth("nobody", "secret") }, http.StatusUnauthorized},
+		{"GET", "/api/v1/memos", func(req *http.Request) { req.SetBasicAuth("mattn", "secret") }, http.StatusOK},
+		{"GET", "/api/v1/memos", func(req *http.Request) { req.Header.Set("authorization", "Bearer wrong") }, http.StatusUnauthorized},
+		{"GET", "/api/v1/memos", func(req *http.Request) { req.Header.Set("authorization", "Bearer token") }, http.StatusOK},
+		{"DELETE", "/api/v1/memos/2017-01-01-foo.md", func(req *http.Request) { req.Header.Set("authorization", "Bearer token") }, http.StatusForbidden},
+		{"GET", "/edit/2017-01-01-foo.md", func(req *http.Request) { req.Header.Set("authorization", "Bearer token") }, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(""))
+		if test.auth != nil {
+			test.auth(req)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("%s %s: want status %d but got %d", test.method, test.path, test.status, w.Code)
+		}
+	}
+	if _, err := s.Get("2017-01-01-foo.md"); err != nil {
+		t.Fatal("memo must not be deleted in read-only mode")
+	}
+}
+
+func TestGuardHost(t *testing.T) {
+	srv := &server{cfg: &config{}, s: store.NewMemory(), idx: index.New(), localOnly: true}
+	h := srv.handler()
+
+	for host, status := range map[string]int{
+		"localhost:8080":        http.StatusOK,
+		"127.0.0.1:8080":        http.StatusOK,
+		"[::1]:8080":            http.StatusOK,
+		"localhost":             http.StatusOK,
+		"evil.example.com:8080": http.StatusForbidden,
+		"evil.example.com":      http.StatusForbidden,
+	} {
+		req := httptest.NewRequest("GET", "/api/v1/memos", nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: want status %d but got %d", host, status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/shurcooL/github_flavored_markdown"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+	"github.com/mattn/memo/wiki"
+)
+
+// kinds of problems found by check command.
+const (
+	checkLink        = "link"
+	checkAsset       = "asset"
+	checkOrphan      = "orphan"
+	checkFrontMatter = "frontmatter"
+)
+
+var checkCommand = &cli.Command{
+	Name:   "check",
+	Usage:  "check broken links, missing assets, orphans and front matter",
+	Action: cmdCheck,
+	Flags: []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:  "skip",
+			Usage: "skip the check (link, asset, orphan or frontmatter)",
+		},
+	},
+}
+
+// problem is the problem found in the memo.
+type problem struct {
+	Name string
+	Kind string
+	Msg  string
+}
+
+// checkMemos checks all of memos in s. Links to /assets/ are checked against
+// the files in assetsDir.
+func checkMemos(s store.Store, assetsDir string) ([]*problem, error) {
+	names, err := memoNames(s)
+	if err != nil {
+		return nil, err
+	}
+	exists := map[string]bool{}
+	for _, name := range names {
+		exists[name] = true
+	}
+
+	var problems []*problem
+	var docs []*query.Doc
+	targets := map[string][]string{}
+	linked := map[string]bool{}
+	for _, name := range names {
+		memo, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := store.ParseFrontMatter(memo.Body); err != nil {
+			problems = append(problems, &problem{name, checkFrontMatter, err.Error()})
+		}
+		d := query.NewDoc(memo)
+		docs = append(docs, d)
+		targets[name] = wiki.Links(d.Content)
+	}
+
+	g := newLinkGraph(docs, targets)
+	for _, d := range docs {
+		for _, target := range g.missing[d.Name] {
+			problems = append(problems, &problem{d.Name, checkLink, "[[" + target + "]] is not found"})
+		}
+		for _, name := range g.links[d.Name] {
+			if name != d.Name {
+				linked[name] = true
+			}
+		}
+
+		// markdown links in the rendered memo
+		base := &url.URL{Path: "/" + d.Name}
+		b := github_flavored_markdown.Markdown(d.Content)
+		for _, m := range linkPattern.FindAllSubmatch(b, -1) {
+			u, err := url.Parse(html.UnescapeString(string(m[2])))
+			if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+				continue
+			}
+			p := base.ResolveReference(u).Path
+			switch {
+			case strings.HasPrefix(p, "/assets/gfm/"):
+			case strings.HasPrefix(p, "/assets/"):
+				file := filepath.Join(assetsDir, filepath.FromSlash(strings.TrimPrefix(p, "/assets/")))
+				if _, err := os.Stat(file); err != nil {
+					problems = append(problems, &problem{d.Name, checkAsset, p + " is not found"})
+				}
+			case store.IsMemo(p):
+				name := strings.TrimPrefix(p, "/")
+				if !exists[name] {
+					problems = append(problems, &problem{d.Name, checkLink, u.Path + " is not found"})
+				} else if name != d.Name {
+					linked[name] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		if !linked[name] {
+			problems = append(problems, &problem{name, checkOrphan, "no memo links to this memo"})
+		}
+	}
+	return problems, nil
+}
+
+func cmdCheck(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	problems, err := checkMemos(cfg.memoStore(), cfg.AssetsDir)
+	if err != nil {
+		return err
+	}
+	skip := map[string]bool{}
+	for _, kind := range c.StringSlice("skip") {
+		skip[kind] = true
+	}
+
+	istty := isatty.IsTerminal(os.Stdout.Fd())
+	n := 0
+	for _, p := range problems {
+		if skip[p.Kind] {
+			continue
+		}
+		n++
+		if istty {
+			fmt.Fprintf(color.Output, "%s: %s: %s\n", color.GreenString(p.Name), color.RedString(p.Kind), p.Msg)
+		} else {
+			fmt.Printf("%s: %s: %s\n", p.Name, p.Kind, p.Msg)
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("%d problems found", n)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestCheckMemos(t *testing.T) {
+	assets := t.TempDir()
+	if err := os.WriteFile(filepath.Join(assets, "found.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n[[bar]] [[nothing]] [baz](work/2017-01-03-baz.md) [gone](2017-01-09-gone.md)\n![](/assets/found.png) ![](/assets/missing.png)\n"))
+	s.Create("2017-01-02-bar.md", []byte("---\ntitle: [broken\n---\n[[foo]]\n"))
+	s.Create("work/2017-01-03-baz.md", []byte("# Baz\n`[[code]]`\n"))
+	s.Create("2017-01-04-orphan.md", []byte("# Orphan\n[self](2017-01-04-orphan.md)\n"))
+
+	problems, err := checkMemos(s, assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.Name+" "+p.Kind)
+	}
+	sort.Strings(got)
+	want := []string{
+		"2017-01-01-foo.md asset",
+		"2017-01-01-foo.md link",
+		"2017-01-01-foo.md link",
+		"2017-01-02-bar.md frontmatter",
+		"2017-01-04-orphan.md orphan",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+}
+
+func TestCmdCheck(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n[[bar]]\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\n[[foo]] [[nothing]]\n"))
+
+	app := newTestApp(t, s)
+	if err := app.Run([]string{name, "check"}); err == nil {
+		t.Fatal("want error for broken link")
+	}
+	if err := app.Run([]string{name, "check", "--skip", "link"}); err != nil {
+		t.Fatal(err)
+	}
+}
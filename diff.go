@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the edit script from a to b with the longest common
+// subsequence.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// unifiedDiff returns the unified diff from a to b with 3 lines of context.
+// It returns empty string if a and b are same.
+func unifiedDiff(aName, bName, a, b string) string {
+	const context = 3
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var buf strings.Builder
+	for start := 0; start < len(ops); {
+		// find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		// extend the hunk while the changes are close
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			} else if k-last > 2*context {
+				break
+			}
+		}
+		from := max(first-context, start)
+		to := min(last+context+1, len(ops))
+
+		// line numbers at the head of the hunk
+		aLine, bLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				aLine++
+			}
+			if op.kind != '-' {
+				bLine++
+			}
+		}
+		aCount, bCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, op := range ops[from:to] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return buf.String()
+}
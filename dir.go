@@ -0,0 +1,255 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/wiki"
+)
+
+var dirSorts = []string{"name", "date", "mtime", "title"}
+
+// dirPage is the paging, sorting and grouping of the index page. Templates
+// get it with the page function.
+type dirPage struct {
+	Sort    string
+	Month   string // filter by the year (2006) or the month (2006-01)
+	Page    int
+	PerPage int
+	Pages   int
+	Total   int
+	Prev    string
+	Next    string
+	Sorts   []*dirLink
+	Groups  []*dirGroup
+	Archive []*archiveYear
+}
+
+type dirLink struct {
+	Label   string
+	URL     string
+	Count   int
+	Current bool
+}
+
+// dirGroup is the entries in the same month.
+type dirGroup struct {
+	Label   string
+	Entries []entry
+}
+
+type archiveYear struct {
+	Year   string
+	URL    string
+	Count  int
+	Open   bool
+	Months []*dirLink
+}
+
+func (p *dirPage) url(sort, month string, page int) string {
+	v := url.Values{}
+	if sort != "name" {
+		v.Set("sort", sort)
+	}
+	if month != "" {
+		v.Set("month", month)
+	}
+	if page > 1 {
+		v.Set("page", strconv.Itoa(page))
+	}
+	if p.PerPage != apiPerPage {
+		v.Set("per_page", strconv.Itoa(p.PerPage))
+	}
+	if len(v) == 0 {
+		return "/"
+	}
+	return "/?" + v.Encode()
+}
+
+// docCache keeps the metadata and the wiki links of memos not to read the
+// memos which are not modified.
+type docCache struct {
+	mu    sync.Mutex
+	docs  map[string]*query.Doc
+	links map[string][]string
+}
+
+// memoDocs returns the memos without the contents.
+func (srv *server) memoDocs() ([]*query.Doc, error) {
+	docs, _, err := srv.loadDocs()
+	return docs, err
+}
+
+// loadDocs returns the memos without the contents, and the targets of the
+// wiki links in the memos.
+func (srv *server) loadDocs() ([]*query.Doc, map[string][]string, error) {
+	memos, err := srv.s.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srv.docs.mu.Lock()
+	defer srv.docs.mu.Unlock()
+	cache := make(map[string]*query.Doc, len(memos))
+	links := make(map[string][]string, len(memos))
+	docs := make([]*query.Doc, 0, len(memos))
+	for _, m := range memos {
+		d, ok := srv.docs.docs[m.Name]
+		if ok && d.ModTime.Equal(m.ModTime) {
+			links[m.Name] = srv.docs.links[m.Name]
+		} else {
+			memo, err := srv.s.Get(m.Name)
+			if err != nil {
+				continue
+			}
+			d = query.NewDoc(memo)
+			links[m.Name] = wiki.Links(d.Content)
+			d.Content = nil
+		}
+		cache[m.Name] = d
+		docs = append(docs, d)
+	}
+	srv.docs.docs = cache
+	srv.docs.links = links
+	return docs, links, nil
+}
+
+func sortDocs(docs []*query.Doc, by string) {
+	switch by {
+	case "date":
+		sort.SliceStable(docs, func(i, j int) bool {
+			return docs[i].Date().After(docs[j].Date())
+		})
+	case "mtime":
+		sort.SliceStable(docs, func(i, j int) bool {
+			return docs[i].ModTime.After(docs[j].ModTime)
+		})
+	case "title":
+		sort.SliceStable(docs, func(i, j int) bool {
+			return strings.ToLower(docs[i].Meta.Title) < strings.ToLower(docs[j].Meta.Title)
+		})
+	}
+}
+
+// sortTime returns the time used to group the memo.
+func sortTime(d *query.Doc, by string) time.Time {
+	if by == "mtime" {
+		return d.ModTime
+	}
+	return d.Date()
+}
+
+func archive(p *dirPage, docs []*query.Doc) []*archiveYear {
+	var years []*archiveYear
+	yearOf := map[string]*archiveYear{}
+	monthOf := map[string]*dirLink{}
+	for _, d := range docs {
+		month := d.Date().Format("2006-01")
+		year := month[:4]
+		y, ok := yearOf[year]
+		if !ok {
+			y = &archiveYear{Year: year, URL: p.url(p.Sort, year, 1), Open: strings.HasPrefix(p.Month, year)}
+			yearOf[year] = y
+			years = append(years, y)
+		}
+		y.Count++
+		m, ok := monthOf[month]
+		if !ok {
+			m = &dirLink{Label: month, URL: p.url(p.Sort, month, 1), Current: p.Month == month}
+			monthOf[month] = m
+			y.Months = append(y.Months, m)
+		}
+		m.Count++
+	}
+	sort.Slice(years, func(i, j int) bool {
+		return years[i].Year > years[j].Year
+	})
+	for _, y := range years {
+		sort.Slice(y.Months, func(i, j int) bool {
+			return y.Months[i].Label > y.Months[j].Label
+		})
+	}
+	if p.Month == "" && len(years) > 0 {
+		years[0].Open = true
+	}
+	return years
+}
+
+// dirPageOf returns the index page for the request, and the entries in the
+// page.
+func (srv *server) dirPageOf(req *http.Request) (*dirPage, []entry, error) {
+	v := req.URL.Query()
+	p := &dirPage{Sort: v.Get("sort"), Month: v.Get("month")}
+	found := false
+	for _, s := range dirSorts {
+		found = found || s == p.Sort
+	}
+	if !found {
+		p.Sort = "name"
+	}
+	p.Page, p.PerPage = pageParams(v)
+
+	docs, err := srv.memoDocs()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.Archive = archive(p, docs)
+	for _, s := range dirSorts {
+		p.Sorts = append(p.Sorts, &dirLink{Label: s, URL: p.url(s, p.Month, 1), Current: s == p.Sort})
+	}
+
+	if p.Month != "" {
+		var filtered []*query.Doc
+		for _, d := range docs {
+			if strings.HasPrefix(d.Date().Format("2006-01"), p.Month) {
+				filtered = append(filtered, d)
+			}
+		}
+		docs = filtered
+	}
+	sortDocs(docs, p.Sort)
+
+	p.Total = len(docs)
+	p.Pages = (p.Total + p.PerPage - 1) / p.PerPage
+	if p.Pages == 0 {
+		p.Pages = 1
+	}
+	if p.Page > 1 {
+		p.Prev = p.url(p.Sort, p.Month, p.Page-1)
+	}
+	if p.Page < p.Pages {
+		p.Next = p.url(p.Sort, p.Month, p.Page+1)
+	}
+	start, end := paginate(p.Total, p.Page, p.PerPage)
+
+	var entries []entry
+	var group *dirGroup
+	for _, d := range docs[start:end] {
+		e := entry{
+			Name: d.Name,
+			Body: template.HTML(template.HTMLEscapeString(runewidth.Truncate(d.Meta.Title, 80, "..."))),
+			Meta: d.Meta,
+		}
+		entries = append(entries, e)
+
+		label := ""
+		if p.Sort == "date" || p.Sort == "mtime" {
+			label = sortTime(d, p.Sort).Format("2006-01")
+		}
+		if group == nil || group.Label != label {
+			group = &dirGroup{Label: label}
+			p.Groups = append(p.Groups, group)
+		}
+		group.Entries = append(group.Entries, e)
+	}
+	return p, entries, nil
+}
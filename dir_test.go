@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/store"
+)
+
+func TestDirPage(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	s.Create("2017-02-01-bar.md", []byte("# Bar\n"))
+	s.Create("2017-02-02-baz.md", []byte("---\ndate: 2016-12-01\n---\n# Baz\n"))
+	s.Create("2018-01-01-qux.md", []byte("# Qux\n"))
+	srv := &server{cfg: &config{}, s: s, idx: index.New()}
+
+	names := func(entries []entry) string {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		query string
+		want  string
+		pages int
+	}{
+		{"", "2018-01-01-qux.md,2017-02-02-baz.md,2017-02-01-bar.md,2017-01-01-foo.md", 1},
+		{"sort=date", "2018-01-01-qux.md,2017-02-01-bar.md,2017-01-01-foo.md,2017-02-02-baz.md", 1},
+		{"sort=title", "2017-02-01-bar.md,2017-02-02-baz.md,2017-01-01-foo.md,2018-01-01-qux.md", 1},
+		{"sort=title&per_page=3&page=2", "2018-01-01-qux.md", 2},
+		{"sort=date&month=2017", "2017-02-01-bar.md,2017-01-01-foo.md", 1},
+		{"month=2016-12", "2017-02-02-baz.md", 1},
+		{"page=9223372036854775807", "", 1},
+		{"sort=unknown&page=-1", "2018-01-01-qux.md,2017-02-02-baz.md,2017-02-01-bar.md,2017-01-01-foo.md", 1},
+	}
+	for _, test := range tests {
+		p, entries, err := srv.dirPageOf(httptest.NewRequest("GET", "/?"+test.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(entries); got != test.want {
+			t.Errorf("%s: want %s but got %s", test.query, test.want, got)
+		}
+		if p.Pages != test.pages {
+			t.Errorf("%s: want %d pages but got %d", test.query, test.pages, p.Pages)
+		}
+	}
+
+	p, _, err := srv.dirPageOf(httptest.NewRequest("GET", "/?sort=date", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groups []string
+	for _, g := range p.Groups {
+		groups = append(groups, g.Label)
+	}
+	if got, want := strings.Join(groups, ","), "2018-01,2017-02,2017-01,2016-12"; got != want {
+		t.Errorf("want groups %s but got %s", want, got)
+	}
+	var years []string
+	for _, y := range p.Archive {
+		years = append(years, y.Year)
+	}
+	if got, want := strings.Join(years, ","), "2018,2017,2016"; got != want {
+		t.Errorf("want archive %s but got %s", want, got)
+	}
+	if p.Archive[1].Count != 2 || len(p.Archive[1].Months) != 2 {
+		t.Errorf("unexpected archive of 2017: %+v", p.Archive[1])
+	}
+
+	// modified memos are read again
+	s.Update("2017-01-01-foo.md", []byte("# Updated\n"))
+	_, entries, err := srv.dirPageOf(httptest.NewRequest("GET", "/?month=2017-01", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Meta.Title != "Updated" {
+		t.Errorf("want updated title but got %+v", entries)
+	}
+}
+
+func TestDirEntriesEscape(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntags: [x]\n---\n# <img src=x onerror=alert(1)>\n[[bar]]\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\n"))
+	srv := &server{cfg: &config{}, s: s, idx: index.New()}
+	for _, path := range []string{"/tags/x", "/2017-01-02-bar.md"} {
+		body := get(t, srv.handler(), path).Body.String()
+		if strings.Contains(body, "<img") || !strings.Contains(body, "&lt;img src=x onerror=alert(1)&gt;") {
+			t.Errorf("%s: title must be escaped: %s", path, body)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shurcooL/github_flavored_markdown"
+
+	"github.com/mattn/memo/store"
+)
+
+const templateEditContent = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>Edit {{.Name}}</title>
+  <link href="/assets/gfm/gfm.css" media="all" rel="stylesheet" type="text/css" />
+</head>
+<style>
+textarea {width: 100%; height: 60vh; font-family: monospace;}
+.conflict {color: #c00;}
+</style>
+<body>
+<h1>{{.Name}}</h1>
+{{if .Conflict}}<p class="conflict">This memo was modified after you started editing. Saving again overwrites the changes below.</p>
+<details><summary>Current content</summary><pre>{{.Current}}</pre></details>{{end}}
+<form method="post" action="/edit/{{.Name}}">
+  <input type="hidden" name="version" value="{{.Version}}">
+  <textarea name="body">{{.Body}}</textarea>
+  <button type="submit" name="action" value="save">Save</button>
+  <button type="submit" name="action" value="preview">Preview</button>
+  <a href="/{{.Name}}">Cancel</a>
+</form>
+{{with .Preview}}<main class="markdown-body">{{.}}</main>{{end}}
+</body>
+</html>
+`
+
+const templateNewContent = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>New memo</title>
+</head>
+<body>
+<h1>New memo</h1>
+{{with .Error}}<p>{{.}}</p>{{end}}
+<form method="post" action="/new">
+  <p><label>Title <input type="text" name="title" value="{{.Title}}" autofocus></label></p>
+  <p><label>Notebook <input type="text" name="notebook" value="{{.Notebook}}"></label></p>
+  <p><label>Tags <input type="text" name="tags" value="{{.Tags}}" placeholder="comma separated"></label></p>
+  <button type="submit">Create</button>
+  <a href="/">Cancel</a>
+</form>
+</body>
+</html>
+`
+
+type editPage struct {
+	Name     string
+	Body     string
+	Version  string
+	Preview  template.HTML
+	Conflict bool
+	Current  string
+}
+
+type newPage struct {
+	Title    string
+	Notebook string
+	Tags     string
+	Error    string
+}
+
+// memoETag returns the version of the memo used to detect conflicts.
+func memoETag(memo *store.Memo) string {
+	h := sha1.Sum(memo.Body)
+	return fmt.Sprintf(`"%x-%x"`, memo.ModTime.UnixNano(), h[:8])
+}
+
+func (srv *server) handleEdit(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/edit/")
+	// files other than memos, like .git/config, are not edited.
+	if !store.ValidName(name) {
+		srv.renderError(w, http.StatusNotFound, nil)
+		return
+	}
+	memo, err := srv.s.Get(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			srv.renderError(w, http.StatusNotFound, nil)
+		} else {
+			srv.renderError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		srv.render(w, http.StatusOK, "edit", &editPage{
+			Name:    name,
+			Body:    string(memo.Body),
+			Version: memoETag(memo),
+		}, nil)
+	case http.MethodPost:
+		if crossOrigin(req) {
+			srv.renderError(w, http.StatusForbidden, errCrossOrigin)
+			return
+		}
+		page := &editPage{
+			Name:    name,
+			Body:    strings.Replace(req.FormValue("body"), "\r\n", "\n", -1),
+			Version: req.FormValue("version"),
+		}
+		if req.FormValue("action") == "preview" {
+			_, content, _ := store.ParseFrontMatter([]byte(page.Body))
+			page.Preview = template.HTML(github_flavored_markdown.Markdown(content))
+			srv.render(w, http.StatusOK, "edit", page, nil)
+			return
+		}
+		if page.Version == "" {
+			srv.renderError(w, http.StatusBadRequest, errors.New("version required"))
+			return
+		}
+		err := srv.updateMemo(name, page.Version, []byte(page.Body))
+		if errors.Is(err, errConflict) {
+			current, err := srv.s.Get(name)
+			if err != nil {
+				srv.renderError(w, http.StatusInternalServerError, err)
+				return
+			}
+			page.Conflict = true
+			page.Current = string(current.Body)
+			page.Version = memoETag(current)
+			srv.render(w, http.StatusConflict, "edit", page, nil)
+			return
+		}
+		if err != nil {
+			srv.renderError(w, http.StatusInternalServerError, err)
+			return
+		}
+		http.Redirect(w, req, "/"+(&url.URL{Path: name}).EscapedPath(), http.StatusSeeOther)
+	default:
+		srv.renderError(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+var (
+	errConflict    = errors.New("memo was modified")
+	errCrossOrigin = errors.New("cross-origin request")
+)
+
+// crossOrigin returns true if the request is sent from the other site, like
+// the form posted from the other web page.
+func crossOrigin(req *http.Request) bool {
+	switch req.Header.Get("sec-fetch-site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+	origin := req.Header.Get("origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host != req.Host
+}
+
+// updateMemo updates the memo if the version of the memo is not changed. The
+// empty version updates the memo unconditionally.
+func (srv *server) updateMemo(name, version string, body []byte) error {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if version != "" {
+		memo, err := srv.s.Get(name)
+		if err != nil {
+			return err
+		}
+		if memoETag(memo) != version {
+			return errConflict
+		}
+	}
+	if err := srv.s.Update(name, body); err != nil {
+		return err
+	}
+	srv.autoCommit("edit: "+name, name)
+	return nil
+}
+
+func (srv *server) handleNew(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		srv.render(w, http.StatusOK, "new", &newPage{}, nil)
+	case http.MethodPost:
+		if crossOrigin(req) {
+			srv.renderError(w, http.StatusForbidden, errCrossOrigin)
+			return
+		}
+		page := &newPage{
+			Title:    req.FormValue("title"),
+			Notebook: req.FormValue("notebook"),
+			Tags:     req.FormValue("tags"),
+		}
+		var tags []string
+		for _, tag := range strings.Split(page.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		name, err := srv.createMemo(page.Title, page.Notebook, tags, nil)
+		if err != nil {
+			page.Error = err.Error()
+			srv.render(w, http.StatusBadRequest, "new", page, nil)
+			return
+		}
+		http.Redirect(w, req, "/edit/"+(&url.URL{Path: name}).EscapedPath(), http.StatusSeeOther)
+	default:
+		srv.renderError(w, http.StatusMethodNotAllowed, nil)
+	}
+}
+
+// createMemo creates the memo named same as cmdNew. If body is nil, the memo
+// template is used.
+func (srv *server) createMemo(title, notebook string, tags []string, body []byte) (string, error) {
+	now := time.Now()
+	name := memoFile(title, notebook, now)
+	if title == "" {
+		title = now.Format("2006-01-02")
+	}
+	if body == nil {
+		var err error
+		body, err = srv.cfg.renderMemo(title, tags, now)
+		if err != nil {
+			return "", err
+		}
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if err := srv.s.Create(name, body); err != nil {
+		return "", err
+	}
+	srv.autoCommit("new: "+name, name)
+	return name, nil
+}
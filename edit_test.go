@@ -0,0 +1,195 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestEdit(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	ts := newTestServer(t, s)
+
+	memo, _ := s.Get("2017-01-01-foo.md")
+	version := memoETag(memo)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	post := func(path string, form url.Values) *http.Response {
+		t.Helper()
+		resp, err := client.PostForm(ts.URL+path, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := post("/edit/2017-01-01-foo.md", url.Values{"action": {"save"}, "version": {version}, "body": {"# Foo\r\nupdated\r\n"}})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("want status %d but got %d", http.StatusSeeOther, resp.StatusCode)
+	}
+	memo, _ = s.Get("2017-01-01-foo.md")
+	if got := string(memo.Body); got != "# Foo\nupdated\n" {
+		t.Fatalf("want updated body but got %q", got)
+	}
+
+	// saving with the old version conflicts
+	resp = post("/edit/2017-01-01-foo.md", url.Values{"action": {"save"}, "version": {version}, "body": {"# Foo\nstale\n"}})
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("want status %d but got %d", http.StatusConflict, resp.StatusCode)
+	}
+	memo, _ = s.Get("2017-01-01-foo.md")
+	if strings.Contains(string(memo.Body), "stale") {
+		t.Fatal("conflicted edit must not be saved")
+	}
+
+	// saving without the version is rejected
+	resp = post("/edit/2017-01-01-foo.md", url.Values{"action": {"save"}, "body": {"# Foo\nblind\n"}})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("want status %d but got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	memo, _ = s.Get("2017-01-01-foo.md")
+	if strings.Contains(string(memo.Body), "blind") {
+		t.Fatal("edit without the version must not be saved")
+	}
+
+	resp = post("/edit/2017-01-01-nothing.md", url.Values{"action": {"save"}, "body": {"x"}})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("want status %d but got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp = post("/new", url.Values{"title": {"new memo"}, "notebook": {"work"}, "tags": {"a, b"}})
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Fatalf("want status %d but got %d", http.StatusSeeOther, resp.StatusCode)
+	}
+	loc := resp.Header.Get("location")
+	if !strings.HasPrefix(loc, "/edit/work/") || !strings.HasSuffix(loc, "-new-memo.md") {
+		t.Fatalf("unexpected location %q", loc)
+	}
+	meta := memoMeta(s, strings.TrimPrefix(loc, "/edit/"))
+	if !meta.HasTag("a") || !meta.HasTag("b") {
+		t.Fatalf("want tags a and b but got %v", meta.Tags)
+	}
+}
+
+func TestAPIIfMatch(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	ts := newTestServer(t, s)
+
+	resp, err := http.Get(ts.URL + "/api/v1/memos/2017-01-01-foo.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("etag")
+	if etag == "" {
+		t.Fatal("want etag")
+	}
+
+	put := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/api/v1/memos/2017-01-01-foo.md", strings.NewReader(body))
+		req.Header.Set("if-match", etag)
+		req.Header.Set("content-type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := put(`{"body":"# Foo\nbar\n"}`); got != http.StatusOK {
+		t.Fatalf("want status %d but got %d", http.StatusOK, got)
+	}
+	if got := put(`{"body":"# Foo\nbaz\n"}`); got != http.StatusPreconditionFailed {
+		t.Fatalf("want status %d but got %d", http.StatusPreconditionFailed, got)
+	}
+}
+
+func TestEditCrossOrigin(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	ts := newTestServer(t, s)
+
+	memo, _ := s.Get("2017-01-01-foo.md")
+	version := memoETag(memo)
+	tests := []struct {
+		path   string
+		form   url.Values
+		header map[string]string
+		status int
+	}{
+		{"/edit/2017-01-01-foo.md", url.Values{"action": {"save"}, "version": {version}, "body": {"x"}}, map[string]string{"Origin": "http://evil.example.com"}, http.StatusForbidden},
+		{"/edit/2017-01-01-foo.md", url.Values{"action": {"save"}, "version": {version}, "body": {"x"}}, map[string]string{"Sec-Fetch-Site": "cross-site"}, http.StatusForbidden},
+		{"/new", url.Values{"title": {"x"}}, map[string]string{"Origin": "null"}, http.StatusForbidden},
+		{"/new", url.Values{"title": {"x"}}, map[string]string{"Sec-Fetch-Site": "same-site"}, http.StatusForbidden},
+		{"/edit/2017-01-01-foo.md", url.Values{"action": {"preview"}, "body": {"x"}}, map[string]string{"Origin": ts.URL, "Sec-Fetch-Site": "same-origin"}, http.StatusOK},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("POST", ts.URL+test.path, strings.NewReader(test.form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range test.header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %v: want status %d but got %d", test.path, test.header, test.status, resp.StatusCode)
+		}
+	}
+	memo, _ = s.Get("2017-01-01-foo.md")
+	if string(memo.Body) != "# Foo\n" {
+		t.Fatalf("cross-origin edit must not be saved: %q", memo.Body)
+	}
+	if names, _ := memoNames(s); len(names) != 1 {
+		t.Fatalf("cross-origin new must not create the memo: %v", names)
+	}
+}
+
+func TestEditHiddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, ".git", "config")
+	if err := os.MkdirAll(filepath.Dir(config), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte("[core]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ts := newTestServer(t, store.NewFS(dir))
+
+	for _, path := range []string{"/.git/config", "/edit/.git/config"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: want status %d but got %d", path, http.StatusNotFound, resp.StatusCode)
+		}
+	}
+	resp, err := http.PostForm(ts.URL+"/edit/.git/config", url.Values{"action": {"save"}, "version": {"x"}, "body": {"[core]\nfsmonitor = touch pwned\n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST /edit/.git/config: want status %d but got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if b, _ := os.ReadFile(config); string(b) != "[core]\n" {
+		t.Fatalf("want .git/config unchanged but got %q", b)
+	}
+}
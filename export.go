@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/shurcooL/github_flavored_markdown/gfmstyle"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/store"
+)
+
+var exportCommand = &cli.Command{
+	Name:  "export",
+	Usage: "export memo",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "html",
+			Usage:     "export memo as static HTML site",
+			ArgsUsage: "[query]",
+			Action:    cmdExportHTML,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "out",
+					Aliases:  []string{"o"},
+					Usage:    "output directory",
+					Required: true,
+				},
+				&cli.StringSliceFlag{
+					Name:  "tag",
+					Usage: "export memos having the tag",
+				},
+				&cli.BoolFlag{
+					Name:  "all-assets",
+					Usage: "copy all files in assetsdir, not only referenced from the pages",
+				},
+				&cli.StringFlag{
+					Name:  "base-url",
+					Usage: "URL of the site for the feeds (feeds are not exported without it)",
+				},
+			},
+		},
+	},
+}
+
+// exporter writes memos as the static site.
+type exporter struct {
+	cfg    *config
+	s      store.Store
+	out    string
+	base   string // URL of the site
+	tmpl   *templateSet
+	links  *linkGraph      // links between exported memos
+	names  map[string]bool // exported memos
+	tags   map[string]int  // tags of exported memos
+	assets map[string]bool // referenced files in assets directory
+
+	allAssets bool // copy all files in assets directory
+}
+
+// htmlName returns the file name of the exported memo.
+func htmlName(name string) string {
+	return strings.TrimSuffix(name, ".md") + ".html"
+}
+
+// tagFile returns the file name of the exported tag page. Tags are escaped
+// not to write files outside the output directory.
+func tagFile(tag string) string {
+	return "tags/" + escape(tag) + ".html"
+}
+
+var linkPattern = regexp.MustCompile(`(\s(?:href|src)=")([^"]*)(")`)
+
+// feedLinkPattern matches the links to the feeds which are removed when the
+// feeds are not exported.
+var feedLinkPattern = regexp.MustCompile(`[ \t]*<link[^>]*\shref="/(?:feed|rss)\.xml"[^>]*>[ \t]*\r?\n?`)
+
+// target returns the exported file for the URL path served by memo serve,
+// or empty string if the page is not exported.
+func (e *exporter) target(p string) string {
+	switch {
+	case p == "/":
+		return "index.html"
+	case p == "/feed.xml" || p == "/rss.xml":
+		if e.base != "" {
+			return strings.TrimPrefix(p, "/")
+		}
+	case p == "/tags/":
+		return "tags/index.html"
+	case strings.HasPrefix(p, "/tags/"):
+		if tag := strings.TrimPrefix(p, "/tags/"); e.tags[tag] > 0 {
+			return tagFile(tag)
+		}
+	case strings.HasPrefix(p, "/assets/gfm/"):
+		return strings.TrimPrefix(p, "/")
+	case strings.HasPrefix(p, "/assets/"):
+		e.assets[strings.TrimPrefix(p, "/assets/")] = true
+		return strings.TrimPrefix(p, "/")
+	case e.names[strings.TrimPrefix(p, "/")]:
+		return htmlName(strings.TrimPrefix(p, "/"))
+	}
+	return ""
+}
+
+// rewriteLinks rewrites the links in the page served at servePath to the
+// relative links to the exported files.
+func (e *exporter) rewriteLinks(b []byte, servePath, file string) []byte {
+	base := &url.URL{Path: servePath}
+	return linkPattern.ReplaceAllFunc(b, func(m []byte) []byte {
+		sm := linkPattern.FindSubmatch(m)
+		u, err := url.Parse(html.UnescapeString(string(sm[2])))
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			return m
+		}
+		// absolute paths are not cleaned, since tags may have dot segments.
+		p := u.Path
+		if !strings.HasPrefix(p, "/") {
+			p = base.ResolveReference(u).Path
+		}
+		target := e.target(p)
+		if target == "" {
+			return m
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(path.Dir(file)), filepath.FromSlash(target))
+		if err != nil {
+			return m
+		}
+		link := (&url.URL{Path: filepath.ToSlash(rel), Fragment: u.Fragment}).String()
+		return []byte(string(sm[1]) + html.EscapeString(link) + string(sm[3]))
+	})
+}
+
+func (e *exporter) writePage(name string, data interface{}, servePath, file string) error {
+	var buf bytes.Buffer
+	if err := e.tmpl.execute(&buf, name, data, true, nil); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	if e.base == "" {
+		b = feedLinkPattern.ReplaceAll(b, nil)
+	}
+	return e.writeFile(file, e.rewriteLinks(b, servePath, file))
+}
+
+func (e *exporter) writeFile(file string, b []byte) error {
+	file = filepath.Join(e.out, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0644)
+}
+
+// copyAssets copies gfm.css and the files in the assets directory referenced
+// from the pages. The files referenced only from stylesheets and scripts are
+// not copied without allAssets.
+func (e *exporter) copyAssets() error {
+	f, err := gfmstyle.Assets.Open("/gfm.css")
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if err := e.writeFile("assets/gfm/gfm.css", b); err != nil {
+		return err
+	}
+
+	if e.allAssets {
+		if err := e.addAllAssets(); err != nil {
+			return err
+		}
+	}
+	for name := range e.assets {
+		if !fs.ValidPath(name) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(e.cfg.AssetsDir, filepath.FromSlash(name)))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "asset not found: %s\n", name)
+				continue
+			}
+			return err
+		}
+		if err := e.writeFile("assets/"+name, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addAllAssets adds the files in the assets directory to the assets to copy.
+// Hidden files and the output directory are skipped.
+func (e *exporter) addAllAssets() error {
+	dir := e.cfg.AssetsDir
+	out, err := filepath.Abs(e.out)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			// gfm/ is served from gfm.css by memo serve.
+			abs, err := filepath.Abs(p)
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || rel == "gfm" || abs == out) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.Type().IsRegular() && !strings.HasPrefix(d.Name(), ".") {
+			e.assets[rel] = true
+		}
+		return nil
+	})
+}
+
+func (e *exporter) export(names []string) error {
+	tmpl, err := e.cfg.loadTemplates()
+	if err != nil {
+		return err
+	}
+	e.tmpl = tmpl
+	e.links, err = loadLinkGraph(e.s, names)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		e.names[name] = true
+		for _, tag := range memoMeta(e.s, name).Tags {
+			e.tags[tag]++
+		}
+	}
+
+	for _, name := range names {
+		memo, err := e.s.Get(name)
+		if err != nil {
+			return err
+		}
+		me := memoEntry(memo, e.links)
+		me.Backlinks = dirEntries(e.s, e.links.backlinks[name])
+		if err := e.writePage("body", me, "/"+name, htmlName(name)); err != nil {
+			return err
+		}
+	}
+	if err := e.writePage("dir", dirEntries(e.s, names), "/", "index.html"); err != nil {
+		return err
+	}
+	if err := e.writePage("dir", tagEntries(e.tags), "/tags/", "tags/index.html"); err != nil {
+		return err
+	}
+	for tag := range e.tags {
+		var tagged []string
+		for _, name := range names {
+			if memoMeta(e.s, name).HasTag(tag) {
+				tagged = append(tagged, name)
+			}
+		}
+		if err := e.writePage("dir", dirEntries(e.s, tagged), "/tags/"+tag, tagFile(tag)); err != nil {
+			return err
+		}
+	}
+	// the feeds need the absolute URL of the site.
+	if e.base != "" {
+		if err := e.writeFeeds(names); err != nil {
+			return err
+		}
+	}
+	return e.copyAssets()
+}
+
+func (e *exporter) writeFeeds(names []string) error {
+	items, err := feedItems(e.s, names, e.cfg.feedSize(), func(name string) string {
+		return e.base + (&url.URL{Path: htmlName(name)}).EscapedPath()
+	}, func(memo *store.Memo) string {
+		return string(e.rewriteLinks([]byte(memoEntry(memo, e.links).Body), "/"+memo.Name, htmlName(memo.Name)))
+	})
+	if err != nil {
+		return err
+	}
+	b, err := marshalFeed(atom(e.base, items))
+	if err != nil {
+		return err
+	}
+	if err := e.writeFile("feed.xml", b); err != nil {
+		return err
+	}
+	b, err = marshalFeed(rss(e.base, items))
+	if err != nil {
+		return err
+	}
+	return e.writeFile("rss.xml", b)
+}
+
+func cmdExportHTML(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	q, err := parseQuery(c.Args())
+	if err != nil {
+		return err
+	}
+	s := cfg.memoStore()
+	docs, err := findMemos(s, q)
+	if err != nil {
+		return err
+	}
+	tags := c.StringSlice("tag")
+	var names []string
+	for _, doc := range docs {
+		if hasTags(doc.Meta, tags) {
+			names = append(names, doc.Name)
+		}
+	}
+	if len(names) == 0 {
+		return errNoMatch
+	}
+
+	e := &exporter{
+		cfg:    &cfg,
+		s:      s,
+		out:    c.String("out"),
+		base:   c.String("base-url"),
+		names:  map[string]bool{},
+		tags:   map[string]int{},
+		assets: map[string]bool{},
+
+		allAssets: c.Bool("all-assets"),
+	}
+	if e.base != "" && !strings.HasSuffix(e.base, "/") {
+		e.base += "/"
+	}
+	if err := e.export(names); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d memos to %s\n", len(names), e.out)
+	return nil
+}
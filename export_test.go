@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestCmdExportHTML(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntags: [public]\n---\n# Foo\nsee [bar](2017-01-02-bar.md) and [baz](/work/2017-01-03-baz.md#top)\n"))
+	s.Create("2017-01-02-bar.md", []byte("---\ntags: [public]\n---\n# Bar\n"))
+	s.Create("work/2017-01-03-baz.md", []byte("---\ntags: [public, work]\n---\n# Baz\n[foo](../2017-01-01-foo.md)\n"))
+	s.Create("2017-01-04-secret.md", []byte("# Secret\n"))
+
+	out := t.TempDir()
+	runTestApp(t, s, "export", "html", "--out", out, "--tag", "public")
+
+	read := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "2017-01-04-secret.html")); !os.IsNotExist(err) {
+		t.Fatal("memo without the tag must not be exported")
+	}
+
+	foo := read("2017-01-01-foo.html")
+	for _, want := range []string{
+		`href="2017-01-02-bar.html"`,
+		`href="work/2017-01-03-baz.html#top"`,
+		`href="assets/gfm/gfm.css"`,
+		`href="tags/public.html"`,
+	} {
+		if !strings.Contains(foo, want) {
+			t.Errorf("want %s in %s", want, foo)
+		}
+	}
+	if strings.Contains(foo, "/edit/") || strings.Contains(foo, "EventSource") {
+		t.Errorf("static page must not have the links to the server: %s", foo)
+	}
+
+	baz := read("work/2017-01-03-baz.html")
+	for _, want := range []string{`href="../2017-01-01-foo.html"`, `href="../assets/gfm/gfm.css"`} {
+		if !strings.Contains(baz, want) {
+			t.Errorf("want %s in %s", want, baz)
+		}
+	}
+
+	index := read("index.html")
+	if !strings.Contains(index, `href="work/2017-01-03-baz.html"`) || strings.Contains(index, "secret") {
+		t.Errorf("unexpected index: %s", index)
+	}
+	if tags := read("tags/work.html"); !strings.Contains(tags, `href="../work/2017-01-03-baz.html"`) {
+		t.Errorf("unexpected tag page: %s", tags)
+	}
+	read("assets/gfm/gfm.css")
+}
+
+func TestExportTagFile(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntags: [\"../../../pwned\"]\n---\n# Foo\n"))
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "a", "b", "out")
+	runTestApp(t, s, "export", "html", "--out", out)
+
+	if _, err := os.Stat(filepath.Join(dir, "a", "pwned.html")); !os.IsNotExist(err) {
+		t.Fatal("tag page must not be written outside the output directory")
+	}
+	b, err := os.ReadFile(filepath.Join(out, "tags", "..-..-..-pwned.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `href="../2017-01-01-foo.html"`) {
+		t.Errorf("unexpected tag page: %s", b)
+	}
+	foo, err := os.ReadFile(filepath.Join(out, "2017-01-01-foo.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(foo), `href="tags/..-..-..-pwned.html"`) {
+		t.Errorf("link to the tag page is not rewritten: %s", foo)
+	}
+}
+
+func TestExportAllAssets(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n![logo](/assets/logo.png)\n"))
+
+	assets := t.TempDir()
+	for _, name := range []string{"logo.png", "style.css", "img/bg.png", ".secret"} {
+		file := filepath.Join(assets, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(configDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	conf := fmt.Sprintf("assetsdir = %q\n", assets)
+	if err := os.WriteFile(filepath.Join(configDir(), "config.toml"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	exists := func(out, name string) bool {
+		_, err := os.Stat(filepath.Join(out, "assets", filepath.FromSlash(name)))
+		return err == nil
+	}
+	out := t.TempDir()
+	runTestApp(t, s, "export", "html", "--out", out)
+	if !exists(out, "logo.png") || exists(out, "style.css") {
+		t.Fatal("want only the referenced assets")
+	}
+
+	// the output directory in the assets directory is not copied.
+	out = filepath.Join(assets, "public")
+	runTestApp(t, s, "export", "html", "--out", out, "--all-assets")
+	for name, want := range map[string]bool{
+		"logo.png":      true,
+		"style.css":     true,
+		"img/bg.png":    true,
+		".secret":       false,
+		"public/assets": false,
+	} {
+		if got := exists(out, name); got != want {
+			t.Errorf("%s: want %v but got %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+)
+
+const (
+	feedTitle = "Memo Life For You"
+	feedSize  = 20
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+}
+
+// feedItem is the memo in the feed.
+type feedItem struct {
+	Link    string
+	Title   string
+	Date    time.Time
+	ModTime time.Time
+	Content string
+}
+
+// feedItems returns the latest n memos in names. link returns the URL of the
+// memo, and content returns the rendered HTML of the memo.
+func feedItems(s store.Store, names []string, n int, link func(name string) string, content func(memo *store.Memo) string) ([]*feedItem, error) {
+	var docs []*query.Doc
+	memos := map[string]*store.Memo{}
+	for _, name := range names {
+		memo, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		memos[name] = memo
+		docs = append(docs, query.NewDoc(memo))
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Date().After(docs[j].Date())
+	})
+	if len(docs) > n {
+		docs = docs[:n]
+	}
+	var items []*feedItem
+	for _, d := range docs {
+		items = append(items, &feedItem{
+			Link:    link(d.Name),
+			Title:   d.Meta.Title,
+			Date:    d.Date(),
+			ModTime: d.ModTime,
+			Content: content(memos[d.Name]),
+		})
+	}
+	return items, nil
+}
+
+func atom(site string, items []*feedItem) *atomFeed {
+	feed := &atomFeed{
+		Title:  feedTitle,
+		ID:     site,
+		Link:   []atomLink{{Href: site}, {Href: site + "feed.xml", Rel: "self"}},
+		Author: atomAuthor{Name: feedTitle},
+	}
+	var updated time.Time
+	for _, item := range items {
+		if item.ModTime.After(updated) {
+			updated = item.ModTime
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:     item.Title,
+			ID:        item.Link,
+			Link:      atomLink{Href: item.Link},
+			Published: item.Date.Format(time.RFC3339),
+			Updated:   item.ModTime.Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Body: item.Content},
+		})
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+	return feed
+}
+
+func rss(site string, items []*feedItem) *rssFeed {
+	feed := &rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       feedTitle,
+			Link:        site,
+			Description: feedTitle,
+		},
+	}
+	for _, item := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        item.Link,
+			PubDate:     item.Date.Format(time.RFC1123Z),
+			Description: item.Content,
+		})
+	}
+	return feed
+}
+
+// marshalFeed returns the feed as XML document.
+func marshalFeed(feed interface{}) ([]byte, error) {
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+func (cfg *config) feedSize() int {
+	if cfg.FeedSize > 0 {
+		return cfg.FeedSize
+	}
+	return feedSize
+}
+
+func (srv *server) handleFeed(w http.ResponseWriter, req *http.Request) {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	site := scheme + "://" + req.Host + "/"
+
+	names, err := memoNames(srv.s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g, err := srv.linkGraph()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items, err := feedItems(srv.s, names, srv.cfg.feedSize(), func(name string) string {
+		return site + (&url.URL{Path: name}).EscapedPath()
+	}, func(memo *store.Memo) string {
+		return string(memoEntry(memo, g).Body)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var feed interface{}
+	if req.URL.Path == "/rss.xml" {
+		w.Header().Set("content-type", "application/rss+xml; charset=utf-8")
+		feed = rss(site, items)
+	} else {
+		w.Header().Set("content-type", "application/atom+xml; charset=utf-8")
+		feed = atom(site, items)
+	}
+	b, err := marshalFeed(feed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
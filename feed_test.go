@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestHandleFeed(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\nhello\n"))
+	s.Create("2017-01-03-bar.md", []byte("---\ntitle: Bar\ndate: 2016-12-31\n---\nbar\n"))
+	s.Create("2017-01-02-baz.md", []byte("# Baz\n"))
+	ts := newTestServer(t, s)
+
+	resp, err := http.Get(ts.URL + "/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var feed atomFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, e := range feed.Entries {
+		titles = append(titles, e.Title)
+	}
+	// the date in the front matter is prior to the file name
+	if got, want := strings.Join(titles, ","), "Baz,Foo,Bar"; got != want {
+		t.Fatalf("want %s but got %s", want, got)
+	}
+	if got, want := feed.Entries[1].Link.Href, ts.URL+"/2017-01-01-foo.md"; got != want {
+		t.Fatalf("want link %s but got %s", want, got)
+	}
+	if !strings.Contains(feed.Entries[1].Content.Body, "<p>hello</p>") {
+		t.Fatalf("want rendered content but got %q", feed.Entries[1].Content.Body)
+	}
+
+	resp, err = http.Get(ts.URL + "/rss.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var r rssFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Channel.Items) != 3 {
+		t.Fatalf("want 3 items but got %d", len(r.Channel.Items))
+	}
+}
+
+func TestExportFeed(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	s.Create("work/2017-01-02-bar.md", []byte("# Bar\n"))
+
+	out := t.TempDir()
+	runTestApp(t, s, "export", "html", "--out", out, "--base-url", "https://example.com/memo")
+
+	b, err := os.ReadFile(filepath.Join(out, "feed.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `href="https://example.com/memo/work/2017-01-02-bar.html"`) {
+		t.Fatalf("unexpected feed: %s", b)
+	}
+	if _, err := os.Stat(filepath.Join(out, "rss.xml")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportWithoutBaseURL(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+
+	out := t.TempDir()
+	runTestApp(t, s, "export", "html", "--out", out)
+
+	for _, name := range []string{"feed.xml", "rss.xml"} {
+		if _, err := os.Stat(filepath.Join(out, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s must not be exported without --base-url", name)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(out, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "feed.xml") {
+		t.Fatalf("index must not link to the feed: %s", b)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	logCommand = &cli.Command{
+		Name:      "log",
+		Usage:     "show revisions of memo",
+		ArgsUsage: "[memo]",
+		Action:    cmdLog,
+	}
+	diffCommand = &cli.Command{
+		Name:      "diff",
+		Usage:     "show changes of memo",
+		ArgsUsage: "<memo> [rev]",
+		Action:    cmdDiff,
+	}
+	restoreCommand = &cli.Command{
+		Name:      "restore",
+		Usage:     "restore memo to the revision",
+		ArgsUsage: "<memo> <rev>",
+		Action:    cmdRestore,
+	}
+)
+
+var errNotGitRepo = errors.New("memodir is not a git repository (run git init in memodir)")
+
+// gitRepo is the git repository in the memo directory. Commands are run
+// with the git CLI.
+type gitRepo struct {
+	dir string
+}
+
+// gitRepo returns the git repository in the memo directory, or nil if the
+// memo directory is not a git repository.
+func (cfg *config) gitRepo() *gitRepo {
+	if cfg.MemoDir == "" || !fileExists(filepath.Join(cfg.MemoDir, ".git")) {
+		return nil
+	}
+	return &gitRepo{dir: cfg.MemoDir}
+}
+
+func (r *gitRepo) command(args ...string) *exec.Cmd {
+	return exec.Command("git", append([]string{"-C", r.dir, "--literal-pathspecs"}, args...)...)
+}
+
+// output runs git and returns the output. The error has the message of git.
+func (r *gitRepo) output(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := r.command(args...)
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return b, nil
+}
+
+// run runs git with the output to stdout.
+func (r *gitRepo) run(args ...string) error {
+	cmd := r.command(args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// commit commits the changes of the memos in names, including new and
+// deleted memos. Other changes in the repository are not committed.
+func (r *gitRepo) commit(msg string, names ...string) error {
+	// git fails with the memos neither tracked nor existing.
+	b, err := r.output(append([]string{"ls-files", "-z", "--"}, names...)...)
+	if err != nil {
+		return err
+	}
+	tracked := map[string]bool{}
+	for _, name := range strings.Split(string(b), "\x00") {
+		tracked[name] = true
+	}
+	paths := []string{"--"}
+	for _, name := range names {
+		if tracked[name] || fileExists(filepath.Join(r.dir, name)) {
+			paths = append(paths, name)
+		}
+	}
+	if len(paths) == 1 {
+		return nil
+	}
+	if _, err := r.output(append([]string{"add", "-A"}, paths...)...); err != nil {
+		return err
+	}
+	if err := r.command(append([]string{"diff", "--cached", "--quiet"}, paths...)...).Run(); err == nil {
+		return nil // nothing changed
+	}
+	_, err = r.output(append([]string{"commit", "-q", "-m", msg}, paths...)...)
+	return err
+}
+
+// revisions returns the commits changing the memo, newest first.
+func (r *gitRepo) revisions(name string) ([]string, error) {
+	b, err := r.output("log", "--format=%H", "--", name)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(b)), nil
+}
+
+// show returns the memo at the revision.
+func (r *gitRepo) show(rev, name string) ([]byte, error) {
+	return r.output("show", rev+":./"+name)
+}
+
+// autoCommit commits the changes of the memos with msg if the memo directory
+// is a git repository.
+func (cfg *config) autoCommit(msg string, names ...string) error {
+	r := cfg.gitRepo()
+	if r == nil || cfg.NoAutoCommit || len(names) == 0 {
+		return nil
+	}
+	return r.commit(msg, names...)
+}
+
+// autoCommit commits the changes of the memos. The error is logged since the
+// memos are already saved. Callers must hold srv.mu.
+func (srv *server) autoCommit(msg string, names ...string) {
+	if err := srv.cfg.autoCommit(msg, names...); err != nil {
+		log.Printf("warning: %v", err)
+	}
+}
+
+// gitArgs returns the git repository and the memo given with the first
+// argument. The memo may be deleted if it is given with the file name.
+func (cfg *config) gitArgs(c *cli.Context) (*gitRepo, string, error) {
+	r := cfg.gitRepo()
+	if r == nil {
+		return nil, "", errNotGitRepo
+	}
+	if !c.Args().Present() {
+		return r, "", nil
+	}
+	name, err := findMemo(cfg.memoStore(), c.Args().First())
+	if err != nil {
+		return nil, "", err
+	}
+	return r, name, nil
+}
+
+func cmdLog(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	r, name, err := cfg.gitArgs(c)
+	if err != nil {
+		return err
+	}
+	args := []string{"log", "--date=format:%Y-%m-%d %H:%M", "--format=%C(yellow)%h%C(reset) %ad %s"}
+	if name != "" {
+		args = append(args, "--follow", "--", name)
+	} else {
+		args = append(args, "--", ".")
+	}
+	return r.run(args...)
+}
+
+func cmdDiff(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	r, name, err := cfg.gitArgs(c)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return cli.ShowSubcommandHelp(c)
+	}
+	if rev := c.Args().Get(1); rev != "" {
+		return r.run("diff", rev, "--", name)
+	}
+
+	// without the revision, show the changes since the previous revision.
+	revs, err := r.revisions(name)
+	if err != nil {
+		return err
+	}
+	switch len(revs) {
+	case 0:
+		return fmt.Errorf("%s has no revision", name)
+	case 1:
+		return r.run("show", "--format=", revs[0], "--", name)
+	}
+	return r.run("diff", revs[1], "--", name)
+}
+
+func cmdRestore(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	r, name, err := cfg.gitArgs(c)
+	if err != nil {
+		return err
+	}
+	rev := c.Args().Get(1)
+	if name == "" || rev == "" {
+		return cli.ShowSubcommandHelp(c)
+	}
+	b, err := r.show(rev, name)
+	if err != nil {
+		return err
+	}
+	s := cfg.memoStore()
+	_, err = s.Get(name)
+	if err == nil {
+		err = s.Update(name, b)
+	} else if errors.Is(err, os.ErrNotExist) {
+		err = s.Create(name, b)
+	}
+	if err != nil {
+		return err
+	}
+	if err := cfg.autoCommit(fmt.Sprintf("restore: %s to %s", name, rev), name); err != nil {
+		return err
+	}
+	color.Yellow("Restored: %v (%v)", name, rev)
+	return nil
+}
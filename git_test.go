@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func setupTestGitRepo(t *testing.T) *config {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	setupTestHome(t)
+	dir := t.TempDir()
+	t.Setenv("MEMODIR", dir)
+	t.Setenv("GIT_AUTHOR_NAME", "memo")
+	t.Setenv("GIT_AUTHOR_EMAIL", "memo@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "memo")
+	t.Setenv("GIT_COMMITTER_EMAIL", "memo@example.com")
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return &config{MemoDir: dir}
+}
+
+func gitLog(t *testing.T, cfg *config) string {
+	t.Helper()
+
+	b, err := cfg.gitRepo().output("log", "--format=%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestAutoCommit(t *testing.T) {
+	cfg := setupTestGitRepo(t)
+	s := cfg.memoStore()
+	if err := os.WriteFile(cfg.MemoDir+"/untracked.txt", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	if err := cfg.autoCommit("new: 2017-01-01-foo.md", "2017-01-01-foo.md"); err != nil {
+		t.Fatal(err)
+	}
+	// nothing changed
+	if err := cfg.autoCommit("edit: 2017-01-01-foo.md", "2017-01-01-foo.md"); err != nil {
+		t.Fatal(err)
+	}
+	s.Update("2017-01-01-foo.md", []byte("# Foo\nbar\n"))
+	if err := cfg.autoCommit("edit: 2017-01-01-foo.md", "2017-01-01-foo.md"); err != nil {
+		t.Fatal(err)
+	}
+	// memo neither tracked nor existing
+	if err := cfg.autoCommit("delete: nothing.md", "nothing.md"); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "edit: 2017-01-01-foo.md\nnew: 2017-01-01-foo.md\n", gitLog(t, cfg); got != want {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+	b, err := cfg.gitRepo().output("status", "--porcelain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "?? untracked.txt\n"; string(b) != want {
+		t.Fatalf("want %q but got %q", want, b)
+	}
+
+	cfg.NoAutoCommit = true
+	s.Update("2017-01-01-foo.md", []byte("# Foo\n"))
+	if err := cfg.autoCommit("edit: 2017-01-01-foo.md", "2017-01-01-foo.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitLog(t, cfg); strings.Count(got, "\n") != 2 {
+		t.Fatalf("unexpected log %q", got)
+	}
+}
+
+func TestCmdHistory(t *testing.T) {
+	cfg := setupTestGitRepo(t)
+	s := cfg.memoStore()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\nfirst\n"))
+	cfg.autoCommit("new: 2017-01-01-foo.md", "2017-01-01-foo.md")
+	s.Update("2017-01-01-foo.md", []byte("# Foo\nsecond\n"))
+	cfg.autoCommit("edit: 2017-01-01-foo.md", "2017-01-01-foo.md")
+
+	got := runTestApp(t, nil, "log", "2017-01-01-foo.md")
+	if lines := strings.Split(strings.TrimSpace(got), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[0], " edit: 2017-01-01-foo.md") {
+		t.Fatalf("unexpected log %q", got)
+	}
+	got = runTestApp(t, nil, "diff", "2017-01-01-foo.md")
+	if !strings.Contains(got, "-first\n+second\n") {
+		t.Fatalf("unexpected diff %q", got)
+	}
+
+	runTestApp(t, nil, "delete", "--yes", "--permanent", "2017-01-01-foo.md")
+	if want, got := "delete: 2017-01-01-foo.md\n", gitLog(t, cfg); !strings.HasPrefix(got, want) {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+
+	runTestApp(t, nil, "restore", "2017-01-01-foo.md", "HEAD~2")
+	memo, err := s.Get("2017-01-01-foo.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Foo\nfirst\n"; string(memo.Body) != want {
+		t.Fatalf("want %q but got %q", want, memo.Body)
+	}
+	if want, got := "restore: 2017-01-01-foo.md to HEAD~2\n", gitLog(t, cfg); !strings.HasPrefix(got, want) {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+}
+
+func TestServeAutoCommit(t *testing.T) {
+	cfg := setupTestGitRepo(t)
+	srv := &server{cfg: cfg, s: cfg.memoStore()}
+	name, err := srv.createMemo("foo", "", nil, []byte("# foo\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.updateMemo(name, "", []byte("# foo\nbar\n")); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "edit: "+name+"\nnew: "+name+"\n", gitLog(t, cfg); got != want {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+)
+
+const templateGraphContent = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>Graph</title>
+</head>
+<style>
+html, body {margin: 0; height: 100%; overflow: hidden; font-family: sans-serif;}
+header, #info {position: absolute; left: 0; padding: .5em 1em; background-color: rgba(255, 255, 255, .85);}
+header {top: 0;}
+#info {bottom: 0; white-space: pre;}
+#graph {display: block; width: 100%; height: 100%; cursor: grab;}
+</style>
+<body>
+<header>
+<a href="/">Index</a>
+<form action="/graph" style="display: inline"><input type="search" name="tag" placeholder="Tag" value="{{.Tag}}"></form>
+<span id="count"></span>
+</header>
+<canvas id="graph"></canvas>
+<div id="info"></div>
+<script>
+(function() {
+  var canvas = document.getElementById("graph");
+  var ctx = canvas.getContext("2d");
+  var info = document.getElementById("info");
+  var nodes = [], edges = [];
+  var view = {x: 0, y: 0, k: 1};
+  var alpha = 1, drag = null, moved = false, pan = null, hover = null;
+
+  function color(notebook) {
+    if (notebook === "") return "#888";
+    var h = 0;
+    for (var i = 0; i < notebook.length; i++) h = (h * 31 + notebook.charCodeAt(i)) % 360;
+    return "hsl(" + h + ", 60%, 50%)";
+  }
+
+  function tick() {
+    var i, j, a, b, dx, dy, d, f;
+    for (i = 0; i < nodes.length; i++) {
+      a = nodes[i];
+      for (j = i + 1; j < nodes.length; j++) {
+        b = nodes[j];
+        dx = b.x - a.x;
+        dy = b.y - a.y;
+        d = Math.max(dx * dx + dy * dy, 400);
+        f = 300 * alpha / d;
+        a.vx -= dx * f; a.vy -= dy * f;
+        b.vx += dx * f; b.vy += dy * f;
+      }
+    }
+    edges.forEach(function(e) {
+      dx = e.target.x - e.source.x;
+      dy = e.target.y - e.source.y;
+      d = Math.sqrt(dx * dx + dy * dy) || 1;
+      f = (d - 60) / d * 0.1 * alpha;
+      e.source.vx += dx * f; e.source.vy += dy * f;
+      e.target.vx -= dx * f; e.target.vy -= dy * f;
+    });
+    nodes.forEach(function(n) {
+      if (n === drag) {
+        n.vx = n.vy = 0;
+        return;
+      }
+      n.vx = (n.vx - n.x * 0.005 * alpha) * 0.6;
+      n.vy = (n.vy - n.y * 0.005 * alpha) * 0.6;
+      n.x += n.vx;
+      n.y += n.vy;
+    });
+    alpha *= 0.99;
+  }
+
+  function draw() {
+    var w = canvas.clientWidth, h = canvas.clientHeight;
+    ctx.clearRect(0, 0, w, h);
+    ctx.save();
+    ctx.translate(w / 2 + view.x, h / 2 + view.y);
+    ctx.scale(view.k, view.k);
+    edges.forEach(function(e) {
+      var near = hover && (e.source === hover || e.target === hover);
+      ctx.strokeStyle = near ? "#555" : "#ccc";
+      ctx.lineWidth = (near ? 2 : 1) / view.k;
+      ctx.beginPath();
+      ctx.moveTo(e.source.x, e.source.y);
+      ctx.lineTo(e.target.x, e.target.y);
+      ctx.stroke();
+    });
+    ctx.font = 12 / view.k + "px sans-serif";
+    nodes.forEach(function(n) {
+      var near = hover && (n === hover || hover.neighbors[n.id]);
+      ctx.globalAlpha = hover && !near ? 0.3 : 1;
+      ctx.fillStyle = color(n.notebook);
+      ctx.beginPath();
+      ctx.arc(n.x, n.y, n.r, 0, 2 * Math.PI);
+      ctx.fill();
+      if (near || view.k > 1.5) {
+        ctx.fillStyle = "#000";
+        ctx.fillText(n.title, n.x + n.r + 3 / view.k, n.y + 4 / view.k);
+      }
+    });
+    ctx.restore();
+  }
+
+  function frame() {
+    if (alpha > 0.005 || drag) {
+      tick();
+      draw();
+    }
+    requestAnimationFrame(frame);
+  }
+
+  function resize() {
+    var r = window.devicePixelRatio || 1;
+    canvas.width = canvas.clientWidth * r;
+    canvas.height = canvas.clientHeight * r;
+    ctx.setTransform(r, 0, 0, r, 0, 0);
+    draw();
+  }
+
+  // point returns the position of the mouse in the graph.
+  function point(ev) {
+    var r = canvas.getBoundingClientRect();
+    return {
+      x: (ev.clientX - r.left - r.width / 2 - view.x) / view.k,
+      y: (ev.clientY - r.top - r.height / 2 - view.y) / view.k
+    };
+  }
+
+  function find(p) {
+    for (var i = nodes.length - 1; i >= 0; i--) {
+      var n = nodes[i], dx = n.x - p.x, dy = n.y - p.y;
+      if (dx * dx + dy * dy <= (n.r + 2) * (n.r + 2)) return n;
+    }
+    return null;
+  }
+
+  function show(n) {
+    hover = n;
+    canvas.style.cursor = n ? "pointer" : "grab";
+    info.textContent = n ? n.title + "\n" + n.id + (n.tags.length ? "\n#" + n.tags.join(" #") : "") : "";
+    draw();
+  }
+
+  canvas.addEventListener("mousedown", function(ev) {
+    drag = find(point(ev));
+    moved = false;
+    if (!drag) pan = {x: ev.clientX - view.x, y: ev.clientY - view.y};
+  });
+  window.addEventListener("mousemove", function(ev) {
+    if (drag) {
+      var p = point(ev);
+      drag.x = p.x;
+      drag.y = p.y;
+      moved = true;
+      alpha = Math.max(alpha, 0.3);
+    } else if (pan) {
+      view.x = ev.clientX - pan.x;
+      view.y = ev.clientY - pan.y;
+      draw();
+    } else if (ev.target === canvas) {
+      var n = find(point(ev));
+      if (n !== hover) show(n);
+    }
+  });
+  window.addEventListener("mouseup", function() {
+    if (drag && !moved) {
+      location.href = "/" + drag.id.split("/").map(encodeURIComponent).join("/");
+    }
+    drag = pan = null;
+  });
+  canvas.addEventListener("wheel", function(ev) {
+    ev.preventDefault();
+    var r = canvas.getBoundingClientRect();
+    var mx = ev.clientX - r.left - r.width / 2, my = ev.clientY - r.top - r.height / 2;
+    var k = Math.min(10, Math.max(0.1, view.k * Math.exp(-ev.deltaY * 0.002)));
+    view.x = mx - (mx - view.x) / view.k * k;
+    view.y = my - (my - view.y) / view.k * k;
+    view.k = k;
+    draw();
+  }, {passive: false});
+  window.addEventListener("resize", resize);
+
+  fetch("/graph.json" + location.search).then(function(resp) {
+    return resp.json();
+  }).then(function(data) {
+    var byId = {};
+    nodes = data.nodes.map(function(n, i) {
+      var a = i * 2.4, d = 10 * Math.sqrt(i);
+      n.x = d * Math.cos(a);
+      n.y = d * Math.sin(a);
+      n.vx = n.vy = 0;
+      n.neighbors = {};
+      n.degree = 0;
+      byId[n.id] = n;
+      return n;
+    });
+    edges = data.edges.map(function(e) {
+      var s = byId[e.source], t = byId[e.target];
+      s.neighbors[t.id] = t.neighbors[s.id] = true;
+      s.degree++;
+      t.degree++;
+      return {source: s, target: t};
+    });
+    nodes.forEach(function(n) {
+      n.r = 4 + 2 * Math.sqrt(n.degree);
+    });
+    document.getElementById("count").textContent = nodes.length + " memos, " + edges.length + " links";
+    resize();
+    frame();
+  });
+})();
+</script>
+</body>
+</html>
+`
+
+var graphCommand = &cli.Command{
+	Name:      "graph",
+	Usage:     "print the link graph of memos",
+	ArgsUsage: "[query]",
+	Action:    cmdGraph,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Value: "dot",
+			Usage: "output `format` (dot or json)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "show memos having the tag",
+		},
+	},
+}
+
+// graph is the memos and the wiki links between them.
+type graph struct {
+	Nodes []*graphNode `json:"nodes"`
+	Edges []*graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Notebook string   `json:"notebook"`
+	Tags     []string `json:"tags"`
+}
+
+type graphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+type graphPage struct {
+	Tag string
+}
+
+// newGraph returns the graph of docs. Links to the memos not in docs are
+// omitted.
+func newGraph(docs []*query.Doc, g *linkGraph) *graph {
+	gr := &graph{Nodes: []*graphNode{}, Edges: []*graphEdge{}}
+	nodes := map[string]bool{}
+	for _, d := range docs {
+		tags := d.Meta.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		gr.Nodes = append(gr.Nodes, &graphNode{
+			ID:       d.Name,
+			Title:    d.Meta.Title,
+			Notebook: store.Notebook(d.Name),
+			Tags:     tags,
+		})
+		nodes[d.Name] = true
+	}
+	for _, d := range docs {
+		for _, name := range g.links[d.Name] {
+			if name != d.Name && nodes[name] {
+				gr.Edges = append(gr.Edges, &graphEdge{Source: d.Name, Target: name})
+			}
+		}
+	}
+	return gr
+}
+
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s)
+	return `"` + s + `"`
+}
+
+// writeDOT writes the graph in the DOT language of Graphviz. Memos in the
+// notebook are grouped in the cluster.
+func (gr *graph) writeDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	notebooks := map[string][]*graphNode{}
+	for _, n := range gr.Nodes {
+		notebooks[n.Notebook] = append(notebooks[n.Notebook], n)
+	}
+	names := make([]string, 0, len(notebooks))
+	for name := range notebooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(bw, "digraph memo {")
+	fmt.Fprintln(bw, "  node [shape=box];")
+	for _, name := range names {
+		indent := "  "
+		if name != "" {
+			fmt.Fprintf(bw, "  subgraph %s {\n", dotQuote("cluster_"+name))
+			fmt.Fprintf(bw, "    label=%s;\n", dotQuote(name))
+			indent = "    "
+		}
+		for _, n := range notebooks[name] {
+			fmt.Fprintf(bw, "%s%s [label=%s", indent, dotQuote(n.ID), dotQuote(n.Title))
+			if len(n.Tags) > 0 {
+				fmt.Fprintf(bw, ", tooltip=%s", dotQuote(strings.Join(n.Tags, ", ")))
+			}
+			fmt.Fprintln(bw, "];")
+		}
+		if name != "" {
+			fmt.Fprintln(bw, "  }")
+		}
+	}
+	for _, e := range gr.Edges {
+		fmt.Fprintf(bw, "  %s -> %s;\n", dotQuote(e.Source), dotQuote(e.Target))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func (srv *server) handleGraph(w http.ResponseWriter, req *http.Request) {
+	srv.render(w, http.StatusOK, "graph", &graphPage{Tag: req.URL.Query().Get("tag")}, nil)
+}
+
+func (srv *server) handleGraphJSON(w http.ResponseWriter, req *http.Request) {
+	docs, links, err := srv.loadDocs()
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	g := newLinkGraph(docs, links)
+	var tags []string
+	if tag := req.URL.Query().Get("tag"); tag != "" {
+		tags = []string{tag}
+	}
+	var filtered []*query.Doc
+	for _, d := range docs {
+		if hasTags(d.Meta, tags) {
+			filtered = append(filtered, d)
+		}
+	}
+	writeJSON(w, http.StatusOK, newGraph(filtered, g))
+}
+
+func cmdGraph(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	format := c.String("format")
+	if format != "dot" && format != "json" {
+		return fmt.Errorf("unknown format: %s", format)
+	}
+	q, err := parseQuery(c.Args())
+	if err != nil {
+		return err
+	}
+	s := cfg.memoStore()
+	docs, err := findMemos(s, q)
+	if err != nil {
+		return err
+	}
+	tags := c.StringSlice("tag")
+	var filtered []*query.Doc
+	for _, d := range docs {
+		if hasTags(d.Meta, tags) {
+			filtered = append(filtered, d)
+		}
+	}
+	if len(filtered) == 0 {
+		return errNoMatch
+	}
+
+	// links are resolved with all of memos not to resolve them to the
+	// other memos when the memos are filtered.
+	names, err := memoNames(s)
+	if err != nil {
+		return err
+	}
+	g, err := loadLinkGraph(s, names)
+	if err != nil {
+		return err
+	}
+	gr := newGraph(filtered, g)
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(gr)
+	}
+	return gr.writeDOT(os.Stdout)
+}
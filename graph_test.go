@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/index"
+)
+
+func TestCmdGraph(t *testing.T) {
+	setupTestHome(t)
+	s := newLinkTestStore()
+
+	got := runTestApp(t, s, "graph")
+	for _, want := range []string{
+		`"2017-01-01-foo.md" [label="Foo"];`,
+		`subgraph "cluster_work" {`,
+		`"work/2017-01-03-baz.md" [label="Baz Title"];`,
+		`"2017-01-01-foo.md" -> "2017-01-02-bar.md";`,
+		`"work/2017-01-03-baz.md" -> "2017-01-02-bar.md";`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %s in %s", want, got)
+		}
+	}
+
+	var gr graph
+	if err := json.Unmarshal([]byte(runTestApp(t, s, "graph", "--format", "json", "NOT", "notebook:work")), &gr); err != nil {
+		t.Fatal(err)
+	}
+	if len(gr.Nodes) != 2 {
+		t.Fatalf("want 2 nodes but got %d", len(gr.Nodes))
+	}
+	want := []graphEdge{
+		{"2017-01-02-bar.md", "2017-01-01-foo.md"},
+		{"2017-01-01-foo.md", "2017-01-02-bar.md"},
+	}
+	if len(gr.Edges) != len(want) {
+		t.Fatalf("want %v but got %d edges", want, len(gr.Edges))
+	}
+	for i, e := range gr.Edges {
+		if *e != want[i] {
+			t.Errorf("want %v but got %v", want[i], *e)
+		}
+	}
+}
+
+func TestServeGraph(t *testing.T) {
+	s := newLinkTestStore()
+	srv := &server{cfg: &config{}, s: s, idx: index.New()}
+	w := get(t, srv.handler(), "/graph.json")
+	var gr graph
+	if err := json.Unmarshal(w.Body.Bytes(), &gr); err != nil {
+		t.Fatal(err)
+	}
+	if len(gr.Nodes) != 3 || len(gr.Edges) != 4 {
+		t.Fatalf("unexpected graph %s", w.Body.String())
+	}
+	body := get(t, srv.handler(), "/graph?tag=foo").Body.String()
+	if !strings.Contains(body, `fetch("/graph.json" + location.search)`) || !strings.Contains(body, `value="foo"`) {
+		t.Errorf("unexpected body %s", body)
+	}
+}
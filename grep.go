@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+)
+
+type grepMatch struct {
+	Line   int      `json:"line"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+type grepResult struct {
+	Profile string       `json:"profile,omitempty"`
+	File    string       `json:"file"`
+	Title   string       `json:"title"`
+	Count   int          `json:"count"`
+	Matches []*grepMatch `json:"matches"`
+
+	lines []string
+}
+
+func grepPattern(c *cli.Context) (*regexp.Regexp, error) {
+	pat := c.Args().First()
+	if c.Bool("fixed-strings") {
+		pat = regexp.QuoteMeta(pat)
+	}
+	if c.Bool("ignore-case") {
+		pat = "(?i)" + pat
+	}
+	return regexp.Compile(pat)
+}
+
+// grepMemo returns the result of the memo matched with re, or nil.
+func grepMemo(name string, body []byte, re *regexp.Regexp, context int) *grepResult {
+	var result *grepResult
+	lines := strings.Split(strings.TrimRight(strings.Replace(string(body), "\r\n", "\n", -1), "\n"), "\n")
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		if result == nil {
+			result = &grepResult{
+				File:  name,
+				Title: bodyMeta(body).Title,
+				lines: lines,
+			}
+		}
+		m := &grepMatch{Line: i + 1, Text: line}
+		if context > 0 {
+			start, end := i-context, i+context+1
+			if start < 0 {
+				start = 0
+			}
+			if end > len(lines) {
+				end = len(lines)
+			}
+			m.Before = lines[start:i]
+			m.After = lines[i+1 : end]
+		}
+		result.Matches = append(result.Matches, m)
+		result.Count++
+	}
+	return result
+}
+
+// grepMemos returns the results of the memos matched with q and re. The
+// store finds the memos, and only the matched memos are read for the title
+// and the context.
+func grepMemos(s store.Store, q *query.Query, re *regexp.Regexp, context int) ([]*grepResult, error) {
+	matches, err := s.Search(re)
+	if err != nil {
+		return nil, err
+	}
+	var results []*grepResult
+	for i, m := range matches {
+		if i > 0 && matches[i-1].Name == m.Name {
+			continue
+		}
+		memo, err := s.Get(m.Name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !q.Match(query.NewDoc(memo)) {
+			continue
+		}
+		if result := grepMemo(m.Name, memo.Body, re, context); result != nil {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func printGrepResults(results []*grepResult, re *regexp.Regexp, c *cli.Context) error {
+	if c.Bool("json") {
+		if results == nil {
+			results = []*grepResult{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	istty := isatty.IsTerminal(os.Stdout.Fd())
+	file := func(result *grepResult) string {
+		s := result.File
+		if result.Profile != "" {
+			s = result.Profile + ":" + s
+		}
+		if istty {
+			return color.GreenString(s)
+		}
+		return s
+	}
+	line := func(n int) string {
+		if istty {
+			return color.YellowString("%d", n)
+		}
+		return fmt.Sprint(n)
+	}
+	var w io.Writer = os.Stdout
+	if istty {
+		w = color.Output
+	}
+	red := color.New(color.FgRed, color.Bold).SprintFunc()
+	text := func(s string) string {
+		if istty {
+			return re.ReplaceAllStringFunc(s, func(m string) string { return red(m) })
+		}
+		return s
+	}
+
+	if c.Bool("count") {
+		for _, result := range results {
+			fmt.Fprintf(w, "%s:%s\n", file(result), line(result.Count))
+		}
+		return nil
+	}
+
+	context := c.Int("context")
+	for _, result := range results {
+		last := -1
+		for _, m := range result.Matches {
+			start, end := m.Line-1-context, m.Line+context
+			if start < 0 {
+				start = 0
+			}
+			if end > len(result.lines) {
+				end = len(result.lines)
+			}
+			if start <= last {
+				start = last + 1
+			} else if context > 0 && last >= 0 {
+				fmt.Println("--")
+			}
+			for i := start; i < end; i++ {
+				if re.MatchString(result.lines[i]) {
+					fmt.Fprintf(w, "%s:%s:%s\n", file(result), line(i+1), text(result.lines[i]))
+				} else {
+					fmt.Fprintf(w, "%s-%s-%s\n", file(result), line(i+1), result.lines[i])
+				}
+				last = i
+			}
+		}
+		if context > 0 && result != results[len(results)-1] {
+			fmt.Println("--")
+		}
+	}
+	return nil
+}
+
+func cmdGrepBuiltin(c *cli.Context, cfgs []*config, q *query.Query) error {
+	re, err := grepPattern(c)
+	if err != nil {
+		return err
+	}
+	var results []*grepResult
+	for _, cfg := range cfgs {
+		rs, err := grepMemos(cfg.memoStore(), q, re, c.Int("context"))
+		if err != nil {
+			return err
+		}
+		if len(cfgs) > 1 {
+			for _, r := range rs {
+				r.Profile = cfg.Profile
+			}
+		}
+		results = append(results, rs...)
+	}
+	return printGrepResults(results, re, c)
+}
+
+// indexFile returns the path of the search index for the memo directory.
+func (cfg *config) indexFile() string {
+	h := sha1.Sum([]byte(cfg.MemoDir))
+	return filepath.Join(configDir(), fmt.Sprintf("index-%x.gob", h[:4]))
+}
+
+// updateIndex indexes the memos modified since the last update, and saves the
+// index if changed.
+func (cfg *config) updateIndex(idx *index.Index, s store.Store) error {
+	changed, err := idx.Update(s)
+	if err != nil {
+		return err
+	}
+	if changed {
+		return idx.Save(cfg.indexFile())
+	}
+	return nil
+}
+
+func (cfg *config) openIndex(s store.Store) (*index.Index, error) {
+	idx, err := index.Load(cfg.indexFile())
+	if err != nil {
+		// the broken index is rebuilt.
+		idx = index.New()
+	}
+	if err := cfg.updateIndex(idx, s); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+type indexResult struct {
+	Profile string `json:"profile,omitempty"`
+	*index.Result
+}
+
+func cmdGrepIndex(c *cli.Context, cfgs []*config, q *query.Query) error {
+	var results []*indexResult
+	for _, cfg := range cfgs {
+		s := cfg.memoStore()
+		idx, err := cfg.openIndex(s)
+		if err != nil {
+			return err
+		}
+		for _, result := range idx.Search(strings.Join(c.Args().Slice(), " ")) {
+			memo, err := s.Get(result.Name)
+			if err != nil {
+				continue
+			}
+			if !q.Match(query.NewDoc(memo)) {
+				continue
+			}
+			r := &indexResult{Result: result}
+			if len(cfgs) > 1 {
+				r.Profile = cfg.Profile
+			}
+			results = append(results, r)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if c.Bool("json") {
+		if results == nil {
+			results = []*indexResult{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+	istty := isatty.IsTerminal(os.Stdout.Fd())
+	for _, result := range results {
+		file := result.Name
+		if result.Profile != "" {
+			file = result.Profile + ":" + file
+		}
+		if istty {
+			fmt.Fprintf(color.Output, "%s : %s\n", color.GreenString(file), color.YellowString(result.Title))
+		} else {
+			fmt.Println(file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestGrepMemo(t *testing.T) {
+	body := []byte("# Foo\na\nhello World\nb\nc\nHello\n")
+
+	result := grepMemo("2017-01-01-foo.md", body, regexp.MustCompile(`(?i)hello`), 1)
+	if result == nil {
+		t.Fatal("want result but got nil")
+	}
+	if result.Title != "Foo" || result.Count != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	expect := []*grepMatch{
+		{Line: 3, Text: "hello World", Before: []string{"a"}, After: []string{"b"}},
+		{Line: 6, Text: "Hello", Before: []string{"c"}, After: []string{}},
+	}
+	if !reflect.DeepEqual(result.Matches, expect) {
+		t.Fatalf("want %+v but got %+v", expect, result.Matches)
+	}
+
+	if result := grepMemo("2017-01-01-foo.md", body, regexp.MustCompile(`bar`), 0); result != nil {
+		t.Fatalf("want nil but got %+v", result)
+	}
+}
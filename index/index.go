@@ -0,0 +1,270 @@
+// Package index provides a persistent full-text index of memos.
+package index
+
+import (
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mattn/memo/store"
+)
+
+// Doc is an indexed memo.
+type Doc struct {
+	Title   string
+	ModTime time.Time
+	Terms   map[string]int
+	Length  int
+}
+
+// Result is a memo found by Search.
+type Result struct {
+	Name  string  `json:"file"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
+// Index is an inverted index of memos. The zero value is not usable, use New
+// or Load.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[string]*Doc
+	postings map[string]map[string]int
+}
+
+// New returns an empty Index.
+func New() *Index {
+	return &Index{
+		docs:     map[string]*Doc{},
+		postings: map[string]map[string]int{},
+	}
+}
+
+// Load reads the index from the file. It returns an empty Index if the file
+// does not exist.
+func Load(file string) (*Index, error) {
+	idx := New()
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return idx, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(&idx.docs); err != nil {
+		return nil, err
+	}
+	for name, doc := range idx.docs {
+		idx.addPostings(name, doc)
+	}
+	return idx, nil
+}
+
+// Save writes the index to the file.
+func (idx *Index) Save(file string) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(idx.docs)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+func (idx *Index) addPostings(name string, doc *Doc) {
+	for term, n := range doc.Terms {
+		p, ok := idx.postings[term]
+		if !ok {
+			p = map[string]int{}
+			idx.postings[term] = p
+		}
+		p[name] = n
+	}
+}
+
+func (idx *Index) remove(name string) {
+	doc, ok := idx.docs[name]
+	if !ok {
+		return
+	}
+	for term := range doc.Terms {
+		delete(idx.postings[term], name)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, name)
+}
+
+// Add indexes the memo, replacing the previous one of the same name.
+func (idx *Index) Add(name string, modTime time.Time, body []byte) {
+	meta, _ := store.ParseMeta(body)
+	doc := &Doc{
+		Title:   meta.Title,
+		ModTime: modTime,
+		Terms:   map[string]int{},
+	}
+	for _, term := range Tokenize(string(body)) {
+		doc.Terms[term]++
+		doc.Length++
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(name)
+	idx.docs[name] = doc
+	idx.addPostings(name, doc)
+}
+
+// Remove drops the memo from the index.
+func (idx *Index) Remove(name string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(name)
+}
+
+// Update indexes the memos in s modified since the last update, and drops the
+// memos removed from s. It reports whether the index is changed.
+func (idx *Index) Update(s store.Store) (bool, error) {
+	memos, err := s.List()
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	exists := map[string]bool{}
+	for _, memo := range memos {
+		exists[memo.Name] = true
+		idx.mu.RLock()
+		doc, ok := idx.docs[memo.Name]
+		idx.mu.RUnlock()
+		if ok && doc.ModTime.Equal(memo.ModTime) {
+			continue
+		}
+		m, err := s.Get(memo.Name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return changed, err
+		}
+		idx.Add(m.Name, m.ModTime, m.Body)
+		changed = true
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for name := range idx.docs {
+		if !exists[name] {
+			idx.remove(name)
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// Len returns the number of indexed memos.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// expand returns the postings of the CJK character. Runs of CJK characters
+// are indexed as bigrams, so the character is counted in the bigrams starting
+// or ending with it. Callers must hold idx.mu.
+func (idx *Index) expand(c rune) map[string]int {
+	p := map[string]int{}
+	for name, n := range idx.postings[string(c)] {
+		p[name] = n
+	}
+	starts, ends := map[string]int{}, map[string]int{}
+	for term, tp := range idx.postings {
+		r := []rune(term)
+		if len(r) != 2 || !isCJK(r[0]) || !isCJK(r[1]) {
+			continue
+		}
+		for name, n := range tp {
+			if r[0] == c {
+				starts[name] += n
+			}
+			if r[1] == c {
+				ends[name] += n
+			}
+		}
+	}
+	// the character in the middle of the run is in both of the bigrams.
+	for name, n := range starts {
+		p[name] += max(n, ends[name])
+	}
+	for name, n := range ends {
+		if _, ok := starts[name]; !ok {
+			p[name] += n
+		}
+	}
+	return p
+}
+
+// Search returns the memos containing all of the terms in query, ranked by
+// TF-IDF score.
+func (idx *Index) Search(query string) []*Result {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := map[string]float64{}
+	for i, term := range terms {
+		p := idx.postings[term]
+		if r := []rune(term); len(r) == 1 && isCJK(r[0]) {
+			p = idx.expand(r[0])
+		}
+		if len(p) == 0 {
+			return nil
+		}
+		idf := math.Log(1 + float64(len(idx.docs))/float64(len(p)))
+		next := map[string]float64{}
+		for name, n := range p {
+			if _, ok := scores[name]; i > 0 && !ok {
+				continue
+			}
+			tf := float64(n) / float64(idx.docs[name].Length)
+			next[name] = scores[name] + tf*idf
+		}
+		scores = next
+	}
+
+	results := make([]*Result, 0, len(scores))
+	for name, score := range scores {
+		results = append(results, &Result{
+			Name:  name,
+			Title: idx.docs[name].Title,
+			Score: score,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Name > results[j].Name
+	})
+	return results
+}
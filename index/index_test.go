@@ -0,0 +1,71 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Hello, World! 日本語のメモ go_lang 2017")
+	expect := []string{"hello", "world", "日本", "本語", "語の", "のメ", "メモ", "go_lang", "2017"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatalf("want %q but got %q", expect, got)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\nmemo life for you\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\nmemo memo memo\n日本語のメモ\n"))
+
+	idx := New()
+	changed, err := idx.Update(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || idx.Len() != 2 {
+		t.Fatalf("want 2 memos indexed but got %d", idx.Len())
+	}
+
+	results := idx.Search("memo")
+	if len(results) != 2 || results[0].Name != "2017-01-02-bar.md" {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	if results := idx.Search("memo life"); len(results) != 1 || results[0].Title != "Foo" {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	if results := idx.Search("日本語"); len(results) != 1 || results[0].Name != "2017-01-02-bar.md" {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	for _, q := range []string{"日", "語", "モ"} {
+		if results := idx.Search(q); len(results) != 1 || results[0].Name != "2017-01-02-bar.md" {
+			t.Fatalf("%s: unexpected results: %v", q, results)
+		}
+	}
+	if results := idx.Search("東"); len(results) != 0 {
+		t.Fatalf("unexpected results: %v", results)
+	}
+
+	file := filepath.Join(t.TempDir(), "index.gob")
+	if err := idx.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := idx.Update(s); err != nil || changed {
+		t.Fatalf("want unchanged but got %v, %v", changed, err)
+	}
+
+	s.Delete("2017-01-01-foo.md")
+	if changed, err := idx.Update(s); err != nil || !changed {
+		t.Fatalf("want changed but got %v, %v", changed, err)
+	}
+	if results := idx.Search("life"); len(results) != 0 {
+		t.Fatalf("want no results but got %v", results)
+	}
+}
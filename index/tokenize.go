@@ -0,0 +1,49 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+)
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Tokenize splits s into terms. Latin words are lower-cased, and CJK text
+// which has no spaces between words is split into bigrams.
+func Tokenize(s string) []string {
+	var terms []string
+	var word []rune
+	var cjk []rune
+	flush := func() {
+		if len(word) > 0 {
+			terms = append(terms, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+		if len(cjk) == 1 {
+			terms = append(terms, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			terms = append(terms, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			if len(word) > 0 {
+				flush()
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+	"github.com/mattn/memo/wiki"
+)
+
+// linkGraph is the wiki links between memos.
+type linkGraph struct {
+	resolver  *wiki.Resolver
+	links     map[string][]string // memos linked from the memo
+	missing   map[string][]string // targets which are not found
+	backlinks map[string][]string // memos linking to the memo
+}
+
+// newLinkGraph returns the links between docs. targets are the targets of
+// the wiki links in each memo.
+func newLinkGraph(docs []*query.Doc, targets map[string][]string) *linkGraph {
+	pages := make([]wiki.Page, len(docs))
+	for i, d := range docs {
+		pages[i] = wiki.Page{Name: d.Name, Title: d.Meta.Title}
+	}
+	g := &linkGraph{
+		resolver:  wiki.NewResolver(pages),
+		links:     map[string][]string{},
+		missing:   map[string][]string{},
+		backlinks: map[string][]string{},
+	}
+	for _, d := range docs {
+		seen := map[string]bool{}
+		for _, target := range targets[d.Name] {
+			name, ok := g.resolver.Resolve(target)
+			if !ok {
+				g.missing[d.Name] = append(g.missing[d.Name], target)
+				continue
+			}
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			g.links[d.Name] = append(g.links[d.Name], name)
+			if name != d.Name {
+				g.backlinks[name] = append(g.backlinks[name], d.Name)
+			}
+		}
+	}
+	return g
+}
+
+// loadLinkGraph reads the memos in names and returns the links between them.
+func loadLinkGraph(s store.Store, names []string) (*linkGraph, error) {
+	var docs []*query.Doc
+	targets := map[string][]string{}
+	for _, name := range names {
+		memo, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		d := query.NewDoc(memo)
+		targets[name] = wiki.Links(d.Content)
+		docs = append(docs, d)
+	}
+	return newLinkGraph(docs, targets), nil
+}
+
+// wikiMarkdown replaces the wiki links in content with the markdown links.
+// Links not resolved are left as is.
+func wikiMarkdown(content []byte, r *wiki.Resolver) []byte {
+	if r == nil {
+		return content
+	}
+	return wiki.Replace(content, func(target, label string) (string, bool) {
+		name, ok := r.Resolve(target)
+		if !ok {
+			return "", false
+		}
+		label = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(label)
+		return fmt.Sprintf("[%s](/%s)", label, (&url.URL{Path: name}).EscapedPath()), true
+	})
+}
+
+func cmdLinks(c *cli.Context) error {
+	return printLinks(c, func(g *linkGraph, name string) ([]string, []string) {
+		return g.links[name], g.missing[name]
+	})
+}
+
+func cmdBacklinks(c *cli.Context) error {
+	return printLinks(c, func(g *linkGraph, name string) ([]string, []string) {
+		return g.backlinks[name], nil
+	})
+}
+
+func printLinks(c *cli.Context, fn func(g *linkGraph, name string) ([]string, []string)) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	files, err := cfg.memoArgs(c)
+	if err != nil {
+		return err
+	}
+	s := cfg.memoStore()
+	names, err := memoNames(s)
+	if err != nil {
+		return err
+	}
+	g, err := loadLinkGraph(s, names)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		prefix := ""
+		if len(files) > 1 {
+			prefix = file + ": "
+		}
+		links, missing := fn(g, file)
+		for _, name := range links {
+			fmt.Println(prefix + name)
+		}
+		for _, target := range missing {
+			fmt.Fprintf(os.Stderr, "%s[[%s]] (not found)\n", prefix, target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/store"
+)
+
+func newLinkTestStore() store.Store {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\nsee [[bar]] and [[Baz Title|baz]] and [[nothing]]\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\nback to [[2017-01-01-foo.md]]\n"))
+	s.Create("work/2017-01-03-baz.md", []byte("---\ntitle: Baz Title\n---\n[[bar]] `[[foo]]`\n"))
+	return s
+}
+
+func TestCmdLinks(t *testing.T) {
+	setupTestHome(t)
+	s := newLinkTestStore()
+
+	got := runTestApp(t, s, "links", "2017-01-01-foo.md")
+	if want := "2017-01-02-bar.md\nwork/2017-01-03-baz.md\n"; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+	got = runTestApp(t, s, "backlinks", "2017-01-02-bar.md")
+	if want := "work/2017-01-03-baz.md\n2017-01-01-foo.md\n"; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+	got = runTestApp(t, s, "backlinks", "2017-01-01-foo.md")
+	if want := "2017-01-02-bar.md\n"; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+}
+
+func TestServeWikiLinks(t *testing.T) {
+	s := newLinkTestStore()
+	srv := &server{cfg: &config{}, s: s, idx: index.New()}
+	body := get(t, srv.handler(), "/2017-01-02-bar.md").Body.String()
+	for _, want := range []string{
+		`<a href="/2017-01-01-foo.md"`,
+		`<h2>Linked from</h2>`,
+		`<a href="/work/2017-01-03-baz.md">Baz Title</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("want %s in %s", want, body)
+		}
+	}
+	body = get(t, srv.handler(), "/2017-01-01-foo.md").Body.String()
+	if !strings.Contains(body, `<a href="/work/2017-01-03-baz.md"`) || !strings.Contains(body, "[[nothing]]") {
+		t.Errorf("unexpected body %s", body)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// events broadcasts the names of changed memos to the clients.
+type events struct {
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+}
+
+func (e *events) subscribe() chan string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.clients == nil {
+		e.clients = make(map[chan string]struct{})
+	}
+	ch := make(chan string, 16)
+	e.clients[ch] = struct{}{}
+	return ch
+}
+
+func (e *events) unsubscribe(ch chan string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.clients, ch)
+}
+
+func (e *events) publish(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.clients {
+		select {
+		case ch <- name:
+		default:
+			// the client is too slow. drop the event.
+		}
+	}
+}
+
+// snapshot returns the modification times of the memos.
+func (srv *server) snapshot() (map[string]time.Time, error) {
+	memos, err := srv.s.List()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]time.Time, len(memos))
+	for _, memo := range memos {
+		m[memo.Name] = memo.ModTime
+	}
+	return m, nil
+}
+
+// notifyChanges publishes the memos changed since prev, and returns the
+// current snapshot.
+func (srv *server) notifyChanges(prev map[string]time.Time) (map[string]time.Time, error) {
+	curr, err := srv.snapshot()
+	if err != nil {
+		return prev, err
+	}
+	for name, mtime := range curr {
+		if t, ok := prev[name]; !ok || !t.Equal(mtime) {
+			srv.events.publish(name)
+		}
+	}
+	for name := range prev {
+		if _, ok := curr[name]; !ok {
+			srv.events.publish(name)
+		}
+	}
+	return curr, nil
+}
+
+// watch polls the memo directory and notifies the changes.
+func (srv *server) watch(interval time.Duration) {
+	prev, err := srv.snapshot()
+	if err != nil {
+		log.Println(err)
+	}
+	for range time.Tick(interval) {
+		prev, err = srv.notifyChanges(prev)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// handleEvents sends the names of changed memos with Server-Sent Events.
+func (srv *server) handleEvents(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	ch := srv.events.subscribe()
+	defer srv.events.unsubscribe(ch)
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case name := <-ch:
+			fmt.Fprintf(w, "data: /%s\n\n", name)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-req.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
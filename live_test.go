@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestEvents(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\n"))
+	srv := &server{cfg: &config{}, s: s}
+	ch := srv.events.subscribe()
+	defer srv.events.unsubscribe(ch)
+
+	prev, err := srv.snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	s.Update("2017-01-01-foo.md", []byte("# Foo\nupdated\n"))
+	s.Delete("2017-01-02-bar.md")
+	s.Create("2017-01-03-baz.md", []byte("# Baz\n"))
+	if _, err := srv.notifyChanges(prev); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for len(ch) > 0 {
+		got[<-ch] = true
+	}
+	for _, name := range []string{"2017-01-01-foo.md", "2017-01-02-bar.md", "2017-01-03-baz.md"} {
+		if !got[name] {
+			t.Errorf("want event for %s but got %v", name, got)
+		}
+	}
+}
+
+func TestHandleEvents(t *testing.T) {
+	srv := &server{cfg: &config{}, s: store.NewMemory()}
+	ts := httptest.NewServer(srv.handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("content-type"); ct != "text/event-stream" {
+		t.Fatalf("want text/event-stream but got %q", ct)
+	}
+
+	// the handler subscribes before the response header is sent
+	srv.events.publish("2017-01-01-foo.md")
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "data: /2017-01-01-foo.md\n"; line != want {
+		t.Fatalf("want %q but got %q", want, line)
+	}
+}
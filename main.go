@@ -8,10 +8,9 @@ import (
 	"html/template"
 	"io"
 	"io/ioutil"
-	"log"
-	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -26,10 +25,10 @@ import (
 	"github.com/mattn/go-isatty"
 	"github.com/mattn/go-runewidth"
 	"github.com/mattn/go-tty"
-	"github.com/pkg/browser"
-	"github.com/shurcooL/github_flavored_markdown"
-	"github.com/shurcooL/github_flavored_markdown/gfmstyle"
 	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
 )
 
 const (
@@ -49,14 +48,35 @@ const templateDirContent = `
 <head>
   <meta charset="UTF-8">
   <title>Memo Life For You</title>
+  <link href="/feed.xml" rel="alternate" type="application/atom+xml" title="Memo Life For You" />
 </head>
 <style>
 li {list-style-type: none;}
+.archive {float: right; width: 12em;}
 </style>
 <body>
+{{if not static}}<form action="/search"><input type="search" name="q" placeholder="Search"> <a href="/new">New memo</a> <a href="/graph">Graph</a></form>{{end}}
+{{define "entry"}}<li><a href="/{{.Name}}">{{.Name}}</a><dd>{{.Body}}</dd></li>{{end}}
+{{with page}}
+<nav class="archive">{{range .Archive}}
+  <details{{if .Open}} open{{end}}><summary><a href="{{.URL}}">{{.Year}}</a> ({{.Count}})</summary><ul>{{range .Months}}
+    <li>{{if .Current}}<b>{{.Label}}</b>{{else}}<a href="{{.URL}}">{{.Label}}</a>{{end}} ({{.Count}})</li>{{end}}
+  </ul></details>{{end}}
+</nav>
+<p>Sort by {{range .Sorts}}{{if .Current}}<b>{{.Label}}</b>{{else}}<a href="{{.URL}}">{{.Label}}</a>{{end}} {{end}}</p>
+{{range .Groups}}{{with .Label}}<h2>{{.}}</h2>{{end}}
+<ul>{{range .Entries}}
+  {{template "entry" .}}{{end}}
+</ul>{{end}}
+<p>{{with .Prev}}<a href="{{.}}">Prev</a>{{end}} {{.Page}} / {{.Pages}} {{with .Next}}<a href="{{.}}">Next</a>{{end}}</p>
+{{else}}
 <ul>{{range .}}
-  <li><a href="/{{.Name}}">{{.Name}}</a><dd>{{.Body}}</dd></li>{{end}}
+  {{template "entry" .}}{{end}}
 </ul>
+{{end}}
+{{if not static}}<script>
+new EventSource("/events").onmessage = function() { location.reload() };
+</script>{{end}}
 </body>
 </html>
 `
@@ -66,18 +86,33 @@ const templateBodyContent = `
 <html>
 <head>
   <meta charset="UTF-8">
-  <title>{{.Name}}</title>
+  <title>{{or .Meta.Title .Name}}</title>
   <link href="/assets/gfm/gfm.css" media="all" rel="stylesheet" type="text/css" />
 </head>
 <body>
 	<main class="markdown-body">
+	{{with .Meta.Tags}}<p>{{tagLinks .}}</p>{{end}}
 	{{.Body}}
+	{{with .Backlinks}}<h2>Linked from</h2>
+	<ul>{{range .}}
+		<li><a href="/{{.Name}}">{{or .Meta.Title .Name}}</a></li>{{end}}
+	</ul>{{end}}
 	</main>
+{{if not static}}	<p><a href="/edit{{.Name}}">Edit</a></p>
+<script>
+new EventSource("/events").onmessage = function(e) {
+	if (e.data === {{.Name}}) location.reload();
+};
+</script>{{end}}
 </body>
 </html>
 `
 
-const templateMemoContent = `# {{.Title}}
+const templateMemoContent = `{{if .Tags}}---
+tags: [{{.Tags}}]
+---
+
+{{end}}# {{.Title}}
 `
 
 type config struct {
@@ -92,11 +127,38 @@ type config struct {
 	PluginsDir       string `toml:"pluginsdir"`
 	TemplateDirFile  string `toml:"templatedirfile"`
 	TemplateBodyFile string `toml:"templatebodyfile"`
+	TemplateDir      string `toml:"templatedir"`
+
+	FeedSize     int  `toml:"feedsize,omitempty"`
+	NoAutoCommit bool `toml:"noautocommit,omitempty"`
+
+	Serve serveConfig `toml:"serve,omitempty"`
+
+	Profiles map[string]*profile `toml:"profiles,omitempty"`
+	Profile  string              `toml:"-"`
+}
+
+// profile overrides the config with the name.
+type profile struct {
+	MemoDir      string `toml:"memodir"`
+	Editor       string `toml:"editor"`
+	MemoTemplate string `toml:"memotemplate"`
+	PluginsDir   string `toml:"pluginsdir"`
 }
 
+const defaultProfile = "default"
+
+// profileName is the profile given with --profile or MEMO_PROFILE.
+var profileName string
+
 type entry struct {
-	Name string
-	Body template.HTML
+	Name    string
+	Body    template.HTML
+	Meta    *store.Meta
+	Snippet template.HTML
+
+	// Backlinks are the memos linking to the memo.
+	Backlinks []entry
 }
 
 var commands = []*cli.Command{
@@ -105,6 +167,17 @@ var commands = []*cli.Command{
 		Aliases: []string{"n"},
 		Usage:   "create memo",
 		Action:  cmdNew,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "notebook",
+				Aliases: []string{"n"},
+				Usage:   "create memo in the `notebook`",
+			},
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "add tag to the memo",
+			},
+		},
 	},
 	{
 		Name:    "list",
@@ -120,8 +193,22 @@ var commands = []*cli.Command{
 				Name:  "format",
 				Usage: "print the result using a Go template `string`",
 			},
+			&cli.StringSliceFlag{
+				Name:  "tag",
+				Usage: "show memo having the tag",
+			},
+			&cli.StringFlag{
+				Name:    "notebook",
+				Aliases: []string{"n"},
+				Usage:   "show memo in the `notebook`",
+			},
 		},
 	},
+	{
+		Name:   "tags",
+		Usage:  "list tags",
+		Action: cmdTags,
+	},
 	{
 		Name:    "edit",
 		Aliases: []string{"e"},
@@ -134,17 +221,104 @@ var commands = []*cli.Command{
 		Usage:   "view memo",
 		Action:  cmdCat,
 	},
+	{
+		Name:      "rename",
+		Usage:     "rename memo and update links to it",
+		ArgsUsage: "<memo> <new title>",
+		Action:    cmdRename,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "show the changes without renaming",
+			},
+		},
+	},
+	{
+		Name:      "links",
+		Usage:     "list memos linked from the memo",
+		ArgsUsage: "[query]",
+		Action:    cmdLinks,
+	},
+	{
+		Name:      "backlinks",
+		Usage:     "list memos linking to the memo",
+		ArgsUsage: "[query]",
+		Action:    cmdBacklinks,
+	},
 	{
 		Name:    "delete",
 		Aliases: []string{"d"},
 		Usage:   "delete memo",
 		Action:  cmdDelete,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "permanent",
+				Usage: "delete permanently instead of moving to the trash",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"force", "y"},
+				Usage:   "delete without confirmation",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "show memo to be deleted without deleting",
+			},
+			&cli.StringFlag{
+				Name:  "older-than",
+				Usage: "delete only memo dated before the `date` (e.g. 2024-01-01) or the duration (e.g. 30d)",
+			},
+		},
 	},
+	trashCommand,
 	{
 		Name:    "grep",
 		Aliases: []string{"g"},
 		Usage:   "grep memo",
 		Action:  cmdGrep,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "builtin",
+				Usage: "use builtin grep instead of grepcmd",
+			},
+			&cli.BoolFlag{
+				Name:  "index",
+				Usage: "search words with the full-text index",
+			},
+			&cli.StringFlag{
+				Name:    "query",
+				Aliases: []string{"q"},
+				Usage:   "search only memos matched with the `query`",
+			},
+			&cli.BoolFlag{
+				Name:  "all-profiles",
+				Usage: "search memos in all profiles",
+			},
+			&cli.BoolFlag{
+				Name:    "fixed-strings",
+				Aliases: []string{"F"},
+				Usage:   "interpret pattern as a literal string (builtin)",
+			},
+			&cli.BoolFlag{
+				Name:    "ignore-case",
+				Aliases: []string{"i"},
+				Usage:   "ignore case distinctions (builtin)",
+			},
+			&cli.IntFlag{
+				Name:    "context",
+				Aliases: []string{"C"},
+				Usage:   "print `NUM` lines of context (builtin)",
+			},
+			&cli.BoolFlag{
+				Name:    "count",
+				Aliases: []string{"c"},
+				Usage:   "print count of matched lines per memo (builtin)",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the result as JSON (builtin)",
+			},
+		},
 	},
 	{
 		Name:    "config",
@@ -158,6 +332,13 @@ var commands = []*cli.Command{
 			},
 		},
 	},
+	profileCommand,
+	exportCommand,
+	checkCommand,
+	graphCommand,
+	logCommand,
+	diffCommand,
+	restoreCommand,
 	{
 		Name:    "serve",
 		Aliases: []string{"s"},
@@ -166,14 +347,34 @@ var commands = []*cli.Command{
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:  "addr",
-				Value: ":8080",
+				Value: "localhost:8080",
 				Usage: "server address",
 			},
+			&cli.BoolFlag{
+				Name:  "readonly",
+				Usage: "disable editing memos",
+			},
+			&cli.BoolFlag{
+				Name:  "no-browser",
+				Usage: "do not open the browser",
+			},
+			&cli.StringFlag{
+				Name:  "tls-cert",
+				Usage: "certificate file for HTTPS",
+			},
+			&cli.StringFlag{
+				Name:  "tls-key",
+				Usage: "private key file for HTTPS",
+			},
+			&cli.BoolFlag{
+				Name:  "tls-self-signed",
+				Usage: "serve HTTPS with the self-signed certificate in the config directory",
+			},
 		},
 	},
 }
 
-func (cfg *config) load() error {
+func configDir() string {
 	var dir string
 	if runtime.GOOS == "windows" {
 		dir = os.Getenv("APPDATA")
@@ -184,6 +385,85 @@ func (cfg *config) load() error {
 	} else {
 		dir = filepath.Join(os.Getenv("HOME"), ".config", "memo")
 	}
+	return dir
+}
+
+// load reads the config with the current profile. MEMODIR overrides memodir
+// unless the profile is given explicitly.
+func (cfg *config) load() error {
+	err := cfg.loadProfile(profileName)
+	if err != nil {
+		return err
+	}
+	dir := os.Getenv("MEMODIR")
+	if dir != "" && profileName == "" {
+		cfg.MemoDir = dir
+	}
+	return nil
+}
+
+// useProfile overrides the config with the profile.
+func (cfg *config) useProfile(name string) error {
+	if _, ok := cfg.Profiles[defaultProfile]; ok {
+		return fmt.Errorf("[profiles.%s] is not allowed: the top-level configuration is the %s profile", defaultProfile, defaultProfile)
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+	cfg.Profile = name
+	if name == defaultProfile {
+		return nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q is not defined", name)
+	}
+	if p.MemoDir != "" {
+		cfg.MemoDir = p.MemoDir
+	}
+	if p.Editor != "" {
+		cfg.Editor = p.Editor
+	}
+	if p.MemoTemplate != "" {
+		cfg.MemoTemplate = p.MemoTemplate
+	}
+	if p.PluginsDir != "" {
+		cfg.PluginsDir = p.PluginsDir
+	}
+	return nil
+}
+
+// profileNames returns the names of profiles, default first.
+func (cfg *config) profileNames() []string {
+	var names []string
+	for name := range cfg.Profiles {
+		if name != defaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...)
+}
+
+// loadProfiles returns the configs of all profiles.
+func loadProfiles() ([]*config, error) {
+	var base config
+	if err := base.loadProfile(defaultProfile); err != nil {
+		return nil, err
+	}
+	var cfgs []*config
+	for _, name := range base.profileNames() {
+		var cfg config
+		if err := cfg.loadProfile(name); err != nil {
+			return nil, err
+		}
+		cfgs = append(cfgs, &cfg)
+	}
+	return cfgs, nil
+}
+
+func (cfg *config) loadProfile(name string) error {
+	dir := configDir()
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("cannot create directory: %v", err)
 	}
@@ -197,6 +477,9 @@ func (cfg *config) load() error {
 		if err != nil {
 			return err
 		}
+		if err := cfg.useProfile(name); err != nil {
+			return err
+		}
 		cfg.MemoDir = expandPath(cfg.MemoDir)
 		cfg.AssetsDir = expandPath(cfg.AssetsDir)
 		if cfg.PluginsDir == "" {
@@ -208,11 +491,6 @@ func (cfg *config) load() error {
 			cfg.MemoTemplate = filepath.Join(confDir, "template.txt")
 		}
 		cfg.MemoTemplate = expandPath(cfg.MemoTemplate)
-
-		dir := os.Getenv("MEMODIR")
-		if dir != "" {
-			cfg.MemoDir = dir
-		}
 		return nil
 	}
 
@@ -243,7 +521,21 @@ func (cfg *config) load() error {
 	if dir != "" {
 		cfg.MemoDir = dir
 	}
-	return toml.NewEncoder(f).Encode(cfg)
+	err = toml.NewEncoder(f).Encode(cfg)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return cfg.useProfile(name)
+}
+
+// newStore returns the Store for the memo directory.
+var newStore = func(dir string) store.Store {
+	return store.NewFS(dir)
+}
+
+func (cfg *config) memoStore() store.Store {
+	return newStore(cfg.MemoDir)
 }
 
 func expandPath(s string) string {
@@ -257,23 +549,31 @@ func expandPath(s string) string {
 	return os.Expand(s, os.Getenv)
 }
 
+// errNoMatch is returned when no memo matched, to exit with the distinct
+// status from the other errors.
+var errNoMatch = errors.New("No matched entry")
+
 func msg(err error) int {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		if errors.Is(err, errNoMatch) {
+			return 2
+		}
 		return 1
 	}
 	return 0
 }
 
-func filterMarkdown(files []string) []string {
-	var newfiles []string
-	for _, file := range files {
-		if strings.HasSuffix(file, ".md") {
-			newfiles = append(newfiles, file)
-		}
+func memoNames(s store.Store) ([]string, error) {
+	memos, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(memos))
+	for i, memo := range memos {
+		names[i] = memo.Name
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(newfiles)))
-	return newfiles
+	return names, nil
 }
 
 func ask(prompt string) (bool, error) {
@@ -294,30 +594,44 @@ func ask(prompt string) (bool, error) {
 	return r == 'y' || r == 'Y', nil
 }
 
-func run() int {
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = name
 	app.Usage = "Memo Life For You"
 	app.Version = version
 	app.Commands = commands
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "profile",
+			Usage:   "use the `profile` in config.toml",
+			EnvVars: []string{"MEMO_PROFILE"},
+		},
+	}
+	app.Before = func(c *cli.Context) error {
+		profileName = c.String("profile")
+		return nil
+	}
 	app.Action = appRun
+	return app
+}
 
-	return msg(app.Run(os.Args))
+func run() int {
+	return msg(newApp().Run(os.Args))
 }
 
-func firstline(name string) string {
-	b, err := ioutil.ReadFile(name)
+// memoMeta returns the metadata of the memo. The memo having broken front
+// matter is titled with the first line.
+func memoMeta(s store.Store, name string) *store.Meta {
+	memo, err := s.Get(name)
 	if err != nil {
-		return ""
-	}
-	body := string(b)
-	if strings.HasPrefix(body, "---\n") {
-		if pos := strings.Index(body[4:], "---\n"); pos > 0 {
-			body = body[4+pos+4:]
-		}
+		return &store.Meta{}
 	}
-	body = strings.SplitN(strings.TrimSpace(body), "\n", 2)[0]
-	return strings.TrimLeft(body, "# ")
+	return bodyMeta(memo.Body)
+}
+
+func bodyMeta(b []byte) *store.Meta {
+	meta, _ := store.ParseMeta(b)
+	return meta
 }
 
 func cmdList(c *cli.Context) error {
@@ -327,22 +641,19 @@ func cmdList(c *cli.Context) error {
 		return err
 	}
 
-	f, err := os.Open(cfg.MemoDir)
+	q, err := parseQuery(c.Args())
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	files, err := f.Readdirnames(-1)
+	docs, err := findMemos(cfg.memoStore(), q)
 	if err != nil {
 		return err
 	}
-	files = filterMarkdown(files)
 	istty := isatty.IsTerminal(os.Stdout.Fd())
 	col := cfg.Column
 	if col == 0 {
 		col = column
 	}
-	pat := c.Args().First()
 
 	var tmpl *tt.Template
 	if format := c.String("format"); format != "" {
@@ -353,17 +664,40 @@ func cmdList(c *cli.Context) error {
 		tmpl = t
 	}
 
-	fullpath := c.Bool("fullpath")
-	for _, file := range files {
-		if pat != "" && !strings.Contains(file, pat) {
+	tags := c.StringSlice("tag")
+	notebook := escapePath(c.String("notebook"))
+	var filtered []*query.Doc
+	nbw := 0
+	for _, doc := range docs {
+		if !hasTags(doc.Meta, tags) {
+			continue
+		}
+		if notebook != "" && !inNotebook(doc.Name, notebook) {
 			continue
 		}
+		filtered = append(filtered, doc)
+		if w := runewidth.StringWidth(store.Notebook(doc.Name)); w > nbw {
+			nbw = w
+		}
+	}
+	if nbw > col {
+		nbw = col
+	}
+
+	fullpath := c.Bool("fullpath")
+	for _, doc := range filtered {
+		file, meta := doc.Name, doc.Meta
 		if tmpl != nil {
 			var b bytes.Buffer
 			err := tmpl.Execute(&b, map[string]interface{}{
-				"File":     file,
-				"Title":    firstline(filepath.Join(cfg.MemoDir, file)),
-				"Fullpath": filepath.Join(cfg.MemoDir, file),
+				"File":       file,
+				"Title":      meta.Title,
+				"Fullpath":   filepath.Join(cfg.MemoDir, file),
+				"Date":       meta.Date,
+				"Tags":       meta.Tags,
+				"Categories": meta.Categories,
+				"Notebook":   store.Notebook(file),
+				"Meta":       meta,
 			})
 			if err != nil {
 				return err
@@ -374,9 +708,17 @@ func cmdList(c *cli.Context) error {
 			if wi == 0 {
 				wi = width
 			}
-			title := runewidth.Truncate(firstline(filepath.Join(cfg.MemoDir, file)), wi-4-col, "...")
-			file = runewidth.FillRight(runewidth.Truncate(file, col, "..."), col)
-			fmt.Fprintf(color.Output, "%s : %s\n", color.GreenString(file), color.YellowString(title))
+			if nbw > 0 {
+				wi -= nbw + 3
+			}
+			title := runewidth.Truncate(meta.Title, wi-4-col, "...")
+			file = runewidth.FillRight(runewidth.Truncate(path.Base(file), col, "..."), col)
+			if nbw > 0 {
+				nb := runewidth.FillRight(runewidth.Truncate(store.Notebook(doc.Name), nbw, "..."), nbw)
+				fmt.Fprintf(color.Output, "%s : %s : %s\n", color.CyanString(nb), color.GreenString(file), color.YellowString(title))
+			} else {
+				fmt.Fprintf(color.Output, "%s : %s\n", color.GreenString(file), color.YellowString(title))
+			}
 		} else {
 			if fullpath {
 				file = filepath.Join(cfg.MemoDir, file)
@@ -387,12 +729,176 @@ func cmdList(c *cli.Context) error {
 	return nil
 }
 
+func parseQuery(args cli.Args) (*query.Query, error) {
+	return query.Parse(strings.Join(args.Slice(), " "))
+}
+
+// checkQuery returns an error if the query has a field which is not in the
+// front matter of any memo in the stores. The memos are read only for such a
+// field.
+func checkQuery(q *query.Query, stores ...store.Store) error {
+	var keys map[string]bool
+	var err error
+	qerr := q.CheckFields(func(field string) bool {
+		if keys != nil {
+			return keys[field]
+		}
+		keys = map[string]bool{}
+		for _, s := range stores {
+			var files []string
+			files, err = memoNames(s)
+			if err != nil {
+				return false
+			}
+			for _, file := range files {
+				for k := range memoMeta(s, file).Extra {
+					keys[strings.ToLower(k)] = true
+				}
+			}
+		}
+		return keys[field]
+	})
+	if err != nil {
+		return err
+	}
+	return qerr
+}
+
+// findMemos returns the memos matched with the query. It fails if the query
+// has the field unknown to the memos.
+func findMemos(s store.Store, q *query.Query) ([]*query.Doc, error) {
+	if err := checkQuery(q, s); err != nil {
+		return nil, err
+	}
+	return matchMemos(s, q)
+}
+
+// matchMemos returns the memos matched with the query.
+func matchMemos(s store.Store, q *query.Query) ([]*query.Doc, error) {
+	files, err := memoNames(s)
+	if err != nil {
+		return nil, err
+	}
+	var docs []*query.Doc
+	for _, file := range files {
+		memo, err := s.Get(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if doc := query.NewDoc(memo); q.Match(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// memoArgs returns the memos given with the arguments, which is a memo file
+// name or a query. Without arguments, memos are selected with selectcmd.
+func (cfg *config) memoArgs(c *cli.Context) ([]string, error) {
+	if !c.Args().Present() {
+		return cfg.filterFiles()
+	}
+	if c.Args().Len() == 1 && store.IsMemo(c.Args().First()) {
+		return []string{c.Args().First()}, nil
+	}
+	q, err := parseQuery(c.Args())
+	if err != nil {
+		return nil, err
+	}
+	docs, err := findMemos(cfg.memoStore(), q)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no memo matched with %q", q)
+	}
+	files := make([]string, len(docs))
+	for i, doc := range docs {
+		files[i] = doc.Name
+	}
+	return files, nil
+}
+
+func hasTags(meta *store.Meta, tags []string) bool {
+	for _, tag := range tags {
+		if !meta.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// tagCounts returns the number of memos for each tag.
+func tagCounts(s store.Store) (map[string]int, error) {
+	files, err := memoNames(s)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, file := range files {
+		for _, tag := range memoMeta(s, file).Tags {
+			counts[tag]++
+		}
+	}
+	return counts, nil
+}
+
+func cmdTags(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	counts, err := tagCounts(cfg.memoStore())
+	if err != nil {
+		return err
+	}
+	var tags []string
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	istty := isatty.IsTerminal(os.Stdout.Fd())
+	for _, tag := range tags {
+		if istty {
+			fmt.Fprintf(color.Output, "%s : %s\n", color.GreenString(tag), color.YellowString("%d", counts[tag]))
+		} else {
+			fmt.Printf("%s\t%d\n", tag, counts[tag])
+		}
+	}
+	return nil
+}
+
 func escape(name string) string {
 	s := regexp.MustCompile(`[ <>:"/\\|?*%#]`).ReplaceAllString(name, "-")
 	s = regexp.MustCompile(`--+`).ReplaceAllString(s, "-")
 	return strings.Trim(strings.Replace(s, "--", "-", -1), "- ")
 }
 
+// escapePath escapes each element of the slash-separated path.
+func escapePath(p string) string {
+	p = strings.Trim(filepath.ToSlash(p), "/")
+	if p == "" {
+		return ""
+	}
+	elems := strings.Split(p, "/")
+	for i, elem := range elems {
+		elems[i] = escape(elem)
+	}
+	return strings.Join(elems, "/")
+}
+
+// inNotebook reports whether the memo is in the notebook or its descendants.
+func inNotebook(name, notebook string) bool {
+	nb := store.Notebook(name)
+	return nb == notebook || strings.HasPrefix(nb, notebook+"/")
+}
+
 func (cfg *config) runfilter(command string, r io.Reader, w io.Writer) error {
 	command = os.Expand(command, func(s string) string {
 		switch s {
@@ -455,15 +961,16 @@ func fileExists(filename string) bool {
 	return err == nil
 }
 
-func copyFromStdin(filename string) error {
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
+func copyFromStdin(s store.Store, name string) error {
+	memo, err := s.Get(name)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	_, err = io.Copy(f, os.Stdin)
-	return err
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	return s.Update(name, append(memo.Body, b...))
 }
 
 func cmdNew(c *cli.Context) error {
@@ -474,11 +981,9 @@ func cmdNew(c *cli.Context) error {
 	}
 
 	var title string
-	var file string
 	now := time.Now()
 	if c.Args().Present() {
 		title = c.Args().First()
-		file = now.Format("2006-01-02-") + escape(title) + ".md"
 	} else {
 		fmt.Print("Title: ")
 		scanner := bufio.NewScanner(os.Stdin)
@@ -489,52 +994,75 @@ func cmdNew(c *cli.Context) error {
 			return scanner.Err()
 		}
 		title = scanner.Text()
-		if title == "" {
-			title = now.Format("2006-01-02")
-			file = title + ".md"
-
-		} else {
-			file = now.Format("2006-01-02-") + escape(title) + ".md"
-		}
 	}
-	file = filepath.Join(cfg.MemoDir, file)
-	if fileExists(file) {
-		if !isatty.IsTerminal(os.Stdin.Fd()) {
-			return copyFromStdin(file)
+	file := memoFile(title, c.String("notebook"), now)
+	if title == "" {
+		title = now.Format("2006-01-02")
+	}
+	s := cfg.memoStore()
+	action := "edit"
+	if _, err := s.Get(file); err != nil {
+		b, err := cfg.renderMemo(title, c.StringSlice("tag"), now)
+		if err != nil {
+			return err
+		}
+		err = s.Create(file, b)
+		if err != nil {
+			return err
 		}
-		return cfg.runcmd(cfg.Editor, "", file)
+		action = "new"
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		err = copyFromStdin(s, file)
+	} else {
+		err = cfg.runcmd(cfg.Editor, "", filepath.Join(cfg.MemoDir, file))
+	}
+	if err != nil {
+		return err
+	}
+	return cfg.autoCommit(action+": "+file, file)
+}
+
+// memoFile returns the file name of the new memo. The memo without title is
+// named with the date.
+func memoFile(title, notebook string, now time.Time) string {
+	file := now.Format("2006-01-02-") + escape(title) + ".md"
+	if escape(title) == "" {
+		file = now.Format("2006-01-02") + ".md"
+	}
+	if notebook := escapePath(notebook); notebook != "" {
+		file = notebook + "/" + file
 	}
+	return file
+}
 
+// renderMemo returns the body of the new memo with the memo template.
+func (cfg *config) renderMemo(title string, tags []string, now time.Time) ([]byte, error) {
 	tmplString := templateMemoContent
 
 	if fileExists(cfg.MemoTemplate) {
 		b, err := ioutil.ReadFile(cfg.MemoTemplate)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tmplString = filterTmpl(string(b))
 	}
-	t := template.Must(template.New("memo").Parse(tmplString))
-
-	f, err := os.Create(file)
+	t, err := template.New("memo").Parse(tmplString)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = t.Execute(f, struct {
+	var buf bytes.Buffer
+	err = t.Execute(&buf, struct {
 		Title, Date, Tags, Categories string
 	}{
-		title, now.Format("2006-01-02 15:04"), "", "",
+		title, now.Format("2006-01-02 15:04"), strings.Join(tags, ", "), "",
 	})
-	f.Close()
 	if err != nil {
-		return err
-	}
-
-	if !isatty.IsTerminal(os.Stdin.Fd()) {
-		return copyFromStdin(file)
+		return nil, err
 	}
-	return cfg.runcmd(cfg.Editor, "", file)
+	return buf.Bytes(), nil
 }
 
 var filterReg = regexp.MustCompile(`{{_(.+?)_}}`)
@@ -547,17 +1075,10 @@ func filterTmpl(tmpl string) string {
 }
 
 func (cfg *config) filterFiles() ([]string, error) {
-	var files []string
-	f, err := os.Open(cfg.MemoDir)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	files, err = f.Readdirnames(-1)
+	files, err := memoNames(cfg.memoStore())
 	if err != nil {
 		return nil, err
 	}
-	files = filterMarkdown(files)
 	var buf bytes.Buffer
 	err = cfg.runfilter(cfg.SelectCmd, strings.NewReader(strings.Join(files, "\n")), &buf)
 	if err != nil {
@@ -571,11 +1092,7 @@ func (cfg *config) filterFiles() ([]string, error) {
 	if buf.Len() == 0 {
 		return nil, errors.New("No files selected")
 	}
-	files = strings.Split(strings.TrimSpace(buf.String()), "\n")
-	for i, file := range files {
-		files[i] = filepath.Join(cfg.MemoDir, file)
-	}
-	return files, nil
+	return strings.Split(strings.TrimSpace(buf.String()), "\n"), nil
 }
 
 func cmdEdit(c *cli.Context) error {
@@ -585,30 +1102,28 @@ func cmdEdit(c *cli.Context) error {
 		return err
 	}
 
-	var files []string
-	if c.Args().Present() {
-		files = append(files, filepath.Join(cfg.MemoDir, c.Args().First()))
-	} else {
-		files, err = cfg.filterFiles()
-		if err != nil {
-			return err
-		}
+	files, err := cfg.memoArgs(c)
+	if err != nil {
+		return err
 	}
-	return cfg.runcmd(cfg.Editor, "", files...)
-}
-
-func catFile(file string) error {
-	f, err := os.Open(file)
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Join(cfg.MemoDir, file)
+	}
+	err = cfg.runcmd(cfg.Editor, "", paths...)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	return cfg.autoCommit("edit: "+strings.Join(files, ", "), files...)
+}
 
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		fmt.Println(scanner.Text())
+func catFile(s store.Store, name string) error {
+	memo, err := s.Get(name)
+	if err != nil {
+		return err
 	}
-	return scanner.Err()
+	_, err = os.Stdout.Write(memo.Body)
+	return err
 }
 
 func cmdCat(c *cli.Context) error {
@@ -618,22 +1133,18 @@ func cmdCat(c *cli.Context) error {
 		return err
 	}
 
-	var files []string
-	if c.Args().Present() {
-		files = append(files, filepath.Join(cfg.MemoDir, c.Args().First()))
-	} else {
-		files, err = cfg.filterFiles()
-		if err != nil {
-			return err
-		}
+	files, err := cfg.memoArgs(c)
+	if err != nil {
+		return err
 	}
 
+	s := cfg.memoStore()
 	for i, file := range files {
 		if i > 0 {
 			// Print new page
 			fmt.Println("\x12")
 		}
-		err = catFile(file)
+		err = catFile(s, file)
 		if err != nil {
 			return err
 		}
@@ -648,49 +1159,85 @@ func cmdDelete(c *cli.Context) error {
 		return err
 	}
 
-	if !c.Args().Present() {
-		return errors.New("pattern required")
+	if !c.Args().Present() && !c.IsSet("older-than") {
+		return errors.New("query required")
 	}
-	f, err := os.Open(cfg.MemoDir)
+	q, err := parseQuery(c.Args())
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	files, err := f.Readdirnames(-1)
+	// words do not match titles not to delete more than expected.
+	q.SetDefaultField("name")
+	var limit time.Time
+	if c.IsSet("older-than") {
+		limit, err = olderThan(c.String("older-than"))
+		if err != nil {
+			return err
+		}
+	}
+	s := cfg.memoStore()
+	docs, err := findMemos(s, q)
 	if err != nil {
 		return err
 	}
-	files = filterMarkdown(files)
-	pat := c.Args().First()
 	var args []string
-	for _, file := range files {
-		if pat != "" && !strings.Contains(file, pat) {
+	for _, doc := range docs {
+		if !limit.IsZero() && !doc.Date().Before(limit) {
 			continue
 		}
-		fmt.Println(file)
-		args = append(args, filepath.Join(cfg.MemoDir, file))
+		fmt.Println(doc.Name)
+		args = append(args, doc.Name)
 	}
 	if len(args) == 0 {
-		color.Yellow("%s", "No matched entry")
-		return nil
+		return errNoMatch
 	}
-	color.Red("%s", "Will delete those entry. Are you sure?")
-	answer, err := ask("Are you sure? (y/N)")
-	if answer == false || err != nil {
-		return err
+	if c.Bool("dry-run") {
+		return nil
 	}
-	answer, err = ask("Really? (y/N)")
-	if answer == false || err != nil {
-		return err
+	if !c.Bool("yes") {
+		color.Red("%s", "Will delete those entry. Are you sure?")
+		answer, err := ask("Are you sure? (y/N)")
+		if err != nil {
+			return fmt.Errorf("cannot confirm (use --yes to delete without confirmation): %v", err)
+		}
+		if answer == false {
+			return nil
+		}
+		answer, err = ask("Really? (y/N)")
+		if answer == false || err != nil {
+			return err
+		}
 	}
+	permanent := c.Bool("permanent")
 	for _, arg := range args {
-		err = os.Remove(arg)
+		if permanent {
+			err = s.Delete(arg)
+		} else {
+			err = cfg.moveToTrash(s, arg)
+		}
 		if err != nil {
 			return err
 		}
-		color.Yellow("Deleted: %v", arg)
+		if permanent {
+			color.Yellow("Deleted: %v", arg)
+		} else {
+			color.Yellow("Moved to trash: %v", arg)
+		}
 	}
-	return nil
+	return cfg.autoCommit("delete: "+strings.Join(args, ", "), args...)
+}
+
+// olderThan returns the time of the date (e.g. 2024-01-01) or the duration
+// (e.g. 30d) ago.
+func olderThan(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date or duration: %q", s)
+	}
+	return time.Now().Add(-age), nil
 }
 
 func cmdGrep(c *cli.Context) error {
@@ -703,21 +1250,54 @@ func cmdGrep(c *cli.Context) error {
 	if !c.Args().Present() {
 		return errors.New("pattern required")
 	}
-	f, err := os.Open(cfg.MemoDir)
+	q, err := query.Parse(c.String("query"))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	cfgs := []*config{&cfg}
+	if c.Bool("all-profiles") {
+		cfgs, err = loadProfiles()
+		if err != nil {
+			return err
+		}
+	}
+	var stores []store.Store
+	for _, cfg := range cfgs {
+		stores = append(stores, cfg.memoStore())
+	}
+	if err := checkQuery(q, stores...); err != nil {
+		return err
+	}
+	if c.Bool("index") {
+		return cmdGrepIndex(c, cfgs, q)
+	}
+	if cfg.GrepCmd == "" || c.Bool("builtin") {
+		return cmdGrepBuiltin(c, cfgs, q)
+	}
+	for _, cfg := range cfgs {
+		err1 := cfg.grep(c, q)
+		if err == nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// grep runs grepcmd for the memos matched with the query.
+func (cfg *config) grep(c *cli.Context, q *query.Query) error {
+	var err error
 	var args []string
 	if strings.Index(cfg.GrepCmd, "${FILES}") != -1 {
-		files, err := f.Readdirnames(-1)
-		if err != nil || len(files) == 0 {
+		// the fields are checked with the memos of all profiles.
+		docs, err := matchMemos(cfg.memoStore(), q)
+		if err != nil || len(docs) == 0 {
 			return err
 		}
-		files = filterMarkdown(files)
-		for _, file := range files {
-			args = append(args, filepath.Join(cfg.MemoDir, file))
+		for _, doc := range docs {
+			args = append(args, filepath.Join(cfg.MemoDir, doc.Name))
 		}
+	} else if c.String("query") != "" {
+		return errors.New("--query requires ${FILES} in grepcmd")
 	}
 	if runtime.GOOS == "windows" && len(args) > 0 {
 		pos := 0
@@ -747,17 +1327,7 @@ func cmdConfig(c *cli.Context) error {
 		return err
 	}
 
-	dir := os.Getenv("HOME")
-	if runtime.GOOS == "windows" {
-		dir = os.Getenv("APPDATA")
-		if dir == "" {
-			dir = filepath.Join(os.Getenv("USERPROFILE"), "Application Data", "memo")
-		}
-		dir = filepath.Join(dir, "memo")
-	} else {
-		dir = filepath.Join(dir, ".config", "memo")
-	}
-	file := filepath.Join(dir, "config.toml")
+	file := filepath.Join(configDir(), "config.toml")
 	if c.Bool("cat") {
 		f, err := os.Open(file)
 		if err != nil {
@@ -771,95 +1341,6 @@ func cmdConfig(c *cli.Context) error {
 	return cfg.runcmd(cfg.Editor, "", file)
 }
 
-func cmdServe(c *cli.Context) error {
-	var cfg config
-	err := cfg.load()
-	if err != nil {
-		return err
-	}
-
-	http.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
-		if req.URL.Path == "/" {
-			f, err := os.Open(cfg.MemoDir)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			defer f.Close()
-			files, err := f.Readdirnames(-1)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			files = filterMarkdown(files)
-			var entries []entry
-			for _, file := range files {
-				entries = append(entries, entry{
-					Name: file,
-					Body: template.HTML(runewidth.Truncate(firstline(filepath.Join(cfg.MemoDir, file)), 80, "...")),
-				})
-			}
-			w.Header().Set("content-type", "text/html")
-			cfg.TemplateDirFile = expandPath(cfg.TemplateDirFile)
-			var t *template.Template
-			if cfg.TemplateDirFile == "" {
-				t = template.Must(template.New("dir").Parse(templateDirContent))
-			} else {
-				t, err = template.ParseFiles(cfg.TemplateDirFile)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-			}
-			err = t.Execute(w, entries)
-			if err != nil {
-				log.Println(err)
-			}
-		} else {
-			p := filepath.Join(cfg.MemoDir, escape(req.URL.Path))
-			b, err := ioutil.ReadFile(p)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			body := string(b)
-			if strings.HasPrefix(body, "---\n") {
-				if pos := strings.Index(body[4:], "---\n"); pos > 0 {
-					body = body[4+pos+4:]
-				}
-			}
-			body = string(github_flavored_markdown.Markdown([]byte(body)))
-			cfg.TemplateBodyFile = expandPath(cfg.TemplateBodyFile)
-			var t *template.Template
-			if cfg.TemplateBodyFile == "" {
-				t = template.Must(template.New("body").Parse(templateBodyContent))
-			} else {
-				t, err = template.ParseFiles(cfg.TemplateBodyFile)
-				if err != nil {
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				}
-			}
-			t.Execute(w, entry{
-				Name: req.URL.Path,
-				Body: template.HTML(body),
-			})
-		}
-	})
-	http.Handle("/assets/gfm/", http.StripPrefix("/assets/gfm", http.FileServer(gfmstyle.Assets)))
-	http.Handle("/assets/", http.StripPrefix("/assets", http.FileServer(http.Dir(cfg.AssetsDir))))
-
-	addr := c.String("addr")
-	var url string
-	if strings.HasPrefix(addr, ":") {
-		url = "http://localhost" + addr
-	} else {
-		url = "http://" + addr
-	}
-	browser.OpenURL(url)
-	return http.ListenAndServe(addr, nil)
-}
-
 func listPlugins(fn func(string)) error {
 	var cfg config
 	err := cfg.load()
@@ -942,7 +1423,7 @@ func appRun(c *cli.Context) error {
 	// run external command as a memo subcommand.
 	xargs := args.Tail()
 	cmd := exec.Command(xcmdpath, xargs...)
-	cmd.Env = append(os.Environ(), fmt.Sprintf("MEMODIR=%s", cfg.MemoDir))
+	cmd.Env = append(os.Environ(), fmt.Sprintf("MEMODIR=%s", cfg.MemoDir), fmt.Sprintf("MEMO_PROFILE=%s", cfg.Profile))
 	cmd.Stderr = os.Stderr
 	cmd.Stdout = os.Stdout
 	cmd.Stdin = os.Stdin
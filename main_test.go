@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+)
+
+func setupTestHome(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("APPDATA", t.TempDir())
+	t.Setenv("MEMODIR", "")
+	t.Setenv("MEMO_PROFILE", "")
+}
+
+// newTestApp returns the app using s as the store. If s is nil, memos are
+// stored in the memo directory.
+func newTestApp(t *testing.T, s store.Store) *cli.App {
+	t.Helper()
+
+	if s != nil {
+		old := newStore
+		newStore = func(string) store.Store { return s }
+		t.Cleanup(func() { newStore = old })
+	}
+	return newApp()
+}
+
+func runTestApp(t *testing.T, s store.Store, args ...string) string {
+	t.Helper()
+
+	app := newTestApp(t, s)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	err = app.Run(append([]string{name}, args...))
+	w.Close()
+	b, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCmdList(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntitle: foo\n---\n# Foo\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\n"))
+	s.Create("2017-01-03-baz.md", []byte("# Baz\n"))
+
+	out := runTestApp(t, s, "list", "--format", "{{.File}}:{{.Title}}", "ba")
+	expect := "2017-01-03-baz.md:Baz\n2017-01-02-bar.md:Bar\n"
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+
+	out = runTestApp(t, s, "list", "title:bar", "OR", "-name:ba")
+	expect = "2017-01-02-bar.md\n2017-01-01-foo.md\n"
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+
+	out = runTestApp(t, s, "list", "--format", "{{.Title}}", "foo")
+	if out != "foo\n" {
+		t.Fatalf("want title from front matter but got %q", out)
+	}
+
+	out = runTestApp(t, s, "list")
+	if got := strings.Count(out, "\n"); got != 3 {
+		t.Fatalf("want 3 lines but got %d: %q", got, out)
+	}
+}
+
+func TestCheckQuery(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\nStatus: done\n---\n# Foo\n"))
+
+	for src, ok := range map[string]bool{
+		"foo tag:work":  true,
+		"status:done":   true,
+		"stauts:done":   false,
+		"meeting 10:30": false,
+	} {
+		q, err := query.Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkQuery(q, s); (err == nil) != ok {
+			t.Errorf("%q: unexpected error %v", src, err)
+		}
+	}
+}
+
+func TestCmdTags(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntags: [work, meeting]\n---\n# Foo\n"))
+	s.Create("2017-01-02-bar.md", []byte("---\ntags: [work]\n---\n# Bar\n"))
+	s.Create("2017-01-03-baz.md", []byte("# Baz\n"))
+
+	out := runTestApp(t, s, "tags")
+	expect := "meeting\t1\nwork\t2\n"
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+
+	out = runTestApp(t, s, "list", "--tag", "work", "--tag", "meeting")
+	if out != "2017-01-01-foo.md\n" {
+		t.Fatalf("want memo having both tags but got %q", out)
+	}
+}
+
+func TestCmdDelete(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\n"))
+	s.Create("2099-01-01-baz.md", []byte("# Baz\n"))
+
+	out := runTestApp(t, s, "delete", "--dry-run", "--older-than", "2017-01-02")
+	if out != "2017-01-01-foo.md\n" {
+		t.Fatalf("want memo dated before 2017-01-02 but got %q", out)
+	}
+	if memos, _ := s.List(); len(memos) != 3 {
+		t.Fatalf("want 3 memos but got %d", len(memos))
+	}
+
+	runTestApp(t, s, "delete", "--yes", "--permanent", "--older-than", "30d")
+	memos, _ := s.List()
+	if len(memos) != 1 || memos[0].Name != "2099-01-01-baz.md" {
+		t.Fatalf("unexpected memos: %v", memos)
+	}
+
+	app := newTestApp(t, s)
+	err := app.Run([]string{name, "delete", "--yes", "foo"})
+	if err != errNoMatch || msg(err) != 2 {
+		t.Fatalf("want errNoMatch but got %v", err)
+	}
+}
+
+func TestCmdListNotebook(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	s.Create("work/2017-01-02-bar.md", []byte("# Bar\n"))
+	s.Create("work/meeting/2017-01-03-baz.md", []byte("# Baz\n"))
+
+	out := runTestApp(t, s, "list", "-n", "work")
+	expect := "work/meeting/2017-01-03-baz.md\nwork/2017-01-02-bar.md\n"
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+
+	out = runTestApp(t, s, "list", "--format", "{{.Notebook}}:{{.Title}}")
+	expect = "work/meeting:Baz\nwork:Bar\n:Foo\n"
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	for input, expect := range map[string]string{
+		"/work/2017-01-01-foo.md": "work/2017-01-01-foo.md",
+		"work/a b:c":              "work/a-b-c",
+		"/":                       "",
+	} {
+		if got := escapePath(input); got != expect {
+			t.Errorf("%q: want %q but got %q", input, expect, got)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	setupTestHome(t)
+	dir := t.TempDir()
+	for _, name := range []string{"default", "work"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "2017-01-01-"+name+".md"), []byte("# hello "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(configDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	conf := fmt.Sprintf("memodir = %q\ngrepcmd = \"\"\n[profiles.work]\nmemodir = %q\n",
+		filepath.Join(dir, "default"), filepath.Join(dir, "work"))
+	if err := os.WriteFile(filepath.Join(configDir(), "config.toml"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runTestApp(t, nil, "profile", "list")
+	expect := fmt.Sprintf("default\t%s\nwork\t%s\n", filepath.Join(dir, "default"), filepath.Join(dir, "work"))
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+
+	if out := runTestApp(t, nil, "--profile", "work", "list"); out != "2017-01-01-work.md\n" {
+		t.Fatalf("want memo in work profile but got %q", out)
+	}
+	t.Setenv("MEMO_PROFILE", "work")
+	if out := runTestApp(t, nil, "list"); out != "2017-01-01-work.md\n" {
+		t.Fatalf("want memo in work profile but got %q", out)
+	}
+
+	out = runTestApp(t, nil, "grep", "--all-profiles", "hello")
+	expect = "default:2017-01-01-default.md:1:# hello default\nwork:2017-01-01-work.md:1:# hello work\n"
+	if out != expect {
+		t.Fatalf("want %q but got %q", expect, out)
+	}
+
+	if err := newTestApp(t, nil).Run([]string{name, "--profile", "home", "list"}); err == nil {
+		t.Fatal("want error for undefined profile")
+	}
+
+	// the explicit profile takes precedence over MEMODIR
+	t.Setenv("MEMO_PROFILE", "")
+	t.Setenv("MEMODIR", filepath.Join(dir, "default"))
+	if out := runTestApp(t, nil, "--profile", "work", "list"); out != "2017-01-01-work.md\n" {
+		t.Fatalf("want memo in work profile but got %q", out)
+	}
+	t.Setenv("MEMODIR", filepath.Join(dir, "work"))
+	if out := runTestApp(t, nil, "list"); out != "2017-01-01-work.md\n" {
+		t.Fatalf("want memo in MEMODIR but got %q", out)
+	}
+
+	conf += fmt.Sprintf("[profiles.default]\nmemodir = %q\n", filepath.Join(dir, "work"))
+	if err := os.WriteFile(filepath.Join(configDir(), "config.toml"), []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestApp(t, nil).Run([]string{name, "list"}); err == nil {
+		t.Fatal("want error for [profiles.default]")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+)
+
+var profileCommand = &cli.Command{
+	Name:  "profile",
+	Usage: "manage profiles",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "list profiles",
+			Action: cmdProfileList,
+		},
+	},
+}
+
+func cmdProfileList(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	cfgs, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+	istty := isatty.IsTerminal(os.Stdout.Fd())
+	for _, p := range cfgs {
+		if istty {
+			mark := " "
+			if p.Profile == cfg.Profile {
+				mark = "*"
+			}
+			fmt.Fprintf(color.Output, "%s %s : %s\n", mark, color.GreenString(p.Profile), color.YellowString(p.MemoDir))
+		} else {
+			fmt.Printf("%s\t%s\n", p.Profile, p.MemoDir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/memo/store"
+)
+
+// Doc is a memo to be matched with a query.
+type Doc struct {
+	Name    string
+	ModTime time.Time
+	Meta    *store.Meta
+	Content []byte
+}
+
+// NewDoc returns the Doc of the memo.
+func NewDoc(memo *store.Memo) *Doc {
+	meta, content := store.ParseMeta(memo.Body)
+	return &Doc{
+		Name:    memo.Name,
+		ModTime: memo.ModTime,
+		Meta:    meta,
+		Content: content,
+	}
+}
+
+// Date returns the date of the memo. It is the date in the front matter, the
+// date prefix of the name, or the modification time.
+func (d *Doc) Date() time.Time {
+	if !d.Meta.Date.IsZero() {
+		return d.Meta.Date
+	}
+	if t, ok := store.DateFromName(d.Name); ok {
+		return t
+	}
+	return d.ModTime
+}
+
+// Match reports whether the memo matches the query. The fields are:
+//
+//	name:, file:     the file name contains the value
+//	notebook:        the memo is in the notebook or its descendants
+//	title:           the title contains the value
+//	body:            the content contains the value
+//	tag:             the memo has the tag
+//	category:        the memo has the category
+//	date:, on:       the date is in the day, month or year ("2006-01-02", "2006-01" or "2006")
+//	after:, before:  the date is after or before the day, month or year
+//	any other field  the front matter value contains the value
+//
+// A term without the field matches the file name or the title, unless the
+// field is given with SetDefaultField. Values are compared ignoring case.
+func (q *Query) Match(d *Doc) bool {
+	if q.root == nil {
+		return true
+	}
+	return q.root.match(d)
+}
+
+type node interface {
+	match(d *Doc) bool
+}
+
+type andNode struct{ lhs, rhs node }
+
+func (n *andNode) match(d *Doc) bool { return n.lhs.match(d) && n.rhs.match(d) }
+
+type orNode struct{ lhs, rhs node }
+
+func (n *orNode) match(d *Doc) bool { return n.lhs.match(d) || n.rhs.match(d) }
+
+type notNode struct{ n node }
+
+func (n *notNode) match(d *Doc) bool { return !n.n.match(d) }
+
+type termNode struct {
+	pos   int
+	field string
+	value string
+	start time.Time
+	end   time.Time
+}
+
+func contains(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), substr)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, e := range list {
+		if strings.EqualFold(e, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange returns the range of the day, month or year.
+func parseRange(s string) (time.Time, time.Time, bool) {
+	for _, f := range []struct {
+		layout string
+		next   func(time.Time) time.Time
+	}{
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+		{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+	} {
+		if t, err := time.ParseInLocation(f.layout, s, time.Local); err == nil {
+			return t, f.next(t), true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func newTerm(t *token) (node, error) {
+	n := &termNode{pos: t.pos, field: t.field, value: strings.ToLower(t.value)}
+	switch n.field {
+	case "file":
+		n.field = "name"
+	case "on":
+		n.field = "date"
+	case "tags":
+		n.field = "tag"
+	case "categories":
+		n.field = "category"
+	}
+	switch n.field {
+	case "date", "after", "before":
+		var ok bool
+		n.start, n.end, ok = parseRange(t.value)
+		if !ok {
+			return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("invalid date %q for %q: use 2006-01-02, 2006-01 or 2006", t.value, t.field)}
+		}
+	}
+	return n, nil
+}
+
+// builtinFields are the fields matched without the front matter.
+var builtinFields = map[string]bool{
+	"name":     true,
+	"notebook": true,
+	"title":    true,
+	"body":     true,
+	"tag":      true,
+	"category": true,
+	"date":     true,
+	"after":    true,
+	"before":   true,
+}
+
+// terms calls f for each term in n.
+func terms(n node, f func(*termNode)) {
+	switch n := n.(type) {
+	case *andNode:
+		terms(n.lhs, f)
+		terms(n.rhs, f)
+	case *orNode:
+		terms(n.lhs, f)
+		terms(n.rhs, f)
+	case *notNode:
+		terms(n.n, f)
+	case *termNode:
+		f(n)
+	}
+}
+
+// SetDefaultField makes the terms without the field match the field. Commands
+// removing memos use "name" not to match memos by the title.
+func (q *Query) SetDefaultField(field string) {
+	terms(q.root, func(n *termNode) {
+		if n.field == "" {
+			n.field = field
+		}
+	})
+}
+
+// CheckFields returns an error for the field which is neither builtin nor
+// known, like "tga:work" or "10:30". known is called with the fields of the
+// front matter.
+func (q *Query) CheckFields(known func(field string) bool) error {
+	var err error
+	terms(q.root, func(n *termNode) {
+		if err == nil && n.field != "" && !builtinFields[n.field] && !known(n.field) {
+			err = &Error{Pos: n.pos, Msg: fmt.Sprintf("unknown field %q", n.field)}
+		}
+	})
+	return err
+}
+
+func (n *termNode) match(d *Doc) bool {
+	switch n.field {
+	case "":
+		return contains(d.Name, n.value) || contains(d.Meta.Title, n.value)
+	case "name":
+		return contains(d.Name, n.value)
+	case "notebook":
+		nb := strings.ToLower(store.Notebook(d.Name))
+		return nb == n.value || strings.HasPrefix(nb, n.value+"/")
+	case "title":
+		return contains(d.Meta.Title, n.value)
+	case "body":
+		return contains(string(d.Content), n.value)
+	case "tag":
+		return containsFold(d.Meta.Tags, n.value)
+	case "category":
+		return containsFold(d.Meta.Categories, n.value)
+	case "date":
+		t := d.Date()
+		return !t.Before(n.start) && t.Before(n.end)
+	case "after":
+		return !d.Date().Before(n.end)
+	case "before":
+		return d.Date().Before(n.start)
+	}
+	for k, v := range d.Meta.Extra {
+		if strings.EqualFold(k, n.field) {
+			return contains(fmt.Sprint(v), n.value)
+		}
+	}
+	return false
+}
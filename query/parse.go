@@ -0,0 +1,271 @@
+// Package query implements the query language to find memos.
+//
+// A query is a list of terms combined with AND, OR and NOT. Terms next to
+// each other are combined with AND, and "-" before a term is same as NOT.
+//
+//	tag:work AND title:"release" -draft after:2024-01-01
+//
+// A term is a word, a quoted string, or a field and a value separated by ":".
+// See Match for the fields.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Error is the error of a malformed query.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("query: column %d: %s", e.Pos+1, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokNot
+	tokAnd
+	tokOr
+	tokTerm
+)
+
+type token struct {
+	kind  tokenKind
+	pos   int
+	field string
+	value string
+}
+
+func (t *token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokNot:
+		return "NOT"
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	}
+	if t.field != "" {
+		return fmt.Sprintf("%q", t.field+":"+t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+func isDelim(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')'
+}
+
+func lex(s string) ([]*token, error) {
+	rs := []rune(s)
+	var tokens []*token
+	i := 0
+	quoted := func(start int) (string, error) {
+		var sb strings.Builder
+		for i++; i < len(rs); i++ {
+			switch rs[i] {
+			case '\\':
+				if i+1 < len(rs) {
+					i++
+				}
+				sb.WriteRune(rs[i])
+			case '"':
+				i++
+				return sb.String(), nil
+			default:
+				sb.WriteRune(rs[i])
+			}
+		}
+		return "", &Error{Pos: start, Msg: "unterminated quoted string"}
+	}
+	for i < len(rs) {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, &token{kind: tokLParen, pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, &token{kind: tokRParen, pos: i})
+			i++
+		case r == '-' && i+1 < len(rs) && !isDelim(rs[i+1]):
+			tokens = append(tokens, &token{kind: tokNot, pos: i})
+			i++
+		case r == '"':
+			start := i
+			v, err := quoted(start)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, &token{kind: tokTerm, pos: start, value: v})
+		default:
+			start := i
+			for i < len(rs) && !isDelim(rs[i]) && rs[i] != ':' && rs[i] != '"' {
+				i++
+			}
+			word := string(rs[start:i])
+			if i < len(rs) && rs[i] == ':' {
+				if word == "" {
+					return nil, &Error{Pos: start, Msg: "field name is empty"}
+				}
+				i++
+				var v string
+				if i < len(rs) && rs[i] == '"' {
+					var err error
+					v, err = quoted(i)
+					if err != nil {
+						return nil, err
+					}
+				} else {
+					vs := i
+					for i < len(rs) && !isDelim(rs[i]) {
+						i++
+					}
+					v = string(rs[vs:i])
+				}
+				if v == "" {
+					return nil, &Error{Pos: start, Msg: fmt.Sprintf("value for %q is empty", word)}
+				}
+				tokens = append(tokens, &token{kind: tokTerm, pos: start, field: strings.ToLower(word), value: v})
+				continue
+			}
+			if i < len(rs) && rs[i] == '"' {
+				return nil, &Error{Pos: i, Msg: "unexpected quote"}
+			}
+			switch word {
+			case "AND":
+				tokens = append(tokens, &token{kind: tokAnd, pos: start})
+			case "OR":
+				tokens = append(tokens, &token{kind: tokOr, pos: start})
+			case "NOT":
+				tokens = append(tokens, &token{kind: tokNot, pos: start})
+			default:
+				tokens = append(tokens, &token{kind: tokTerm, pos: start, value: word})
+			}
+		}
+	}
+	return append(tokens, &token{kind: tokEOF, pos: len(rs)}), nil
+}
+
+type parser struct {
+	tokens []*token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() *token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (node, error) {
+	lhs, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		rhs, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &orNode{lhs, rhs}
+	}
+	return lhs, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokNot, tokLParen, tokTerm:
+		default:
+			return lhs, nil
+		}
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &andNode{lhs, rhs}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNot:
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{n}, nil
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokRParen {
+			return nil, &Error{Pos: r.pos, Msg: fmt.Sprintf(`expected ")" for "(" at column %d but got %s`, t.pos+1, r)}
+		}
+		return n, nil
+	case tokTerm:
+		return newTerm(t)
+	}
+	return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("expected term but got %s", t)}
+}
+
+// Query is a parsed query.
+type Query struct {
+	src  string
+	root node
+}
+
+// Parse parses the query. The empty query matches every memo.
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{src: s}
+	if len(tokens) == 1 {
+		return q, nil
+	}
+	p := &parser{tokens: tokens}
+	q.root, err = p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t)}
+	}
+	return q, nil
+}
+
+func (q *Query) String() string {
+	return q.src
+}
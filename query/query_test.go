@@ -0,0 +1,123 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestMatch(t *testing.T) {
+	docs := []*Doc{
+		NewDoc(&store.Memo{
+			Name: "2024-01-01-release-note.md",
+			Body: []byte("---\ntags: [work]\nstatus: done\n---\n# Release note\nv1.0\n"),
+		}),
+		NewDoc(&store.Memo{
+			Name: "2024-02-01-draft.md",
+			Body: []byte("---\ntitle: Release plan\ntags: [work, draft]\ndate: 2024-03-01\n---\nv2.0\n"),
+		}),
+		NewDoc(&store.Memo{
+			Name:    "home/shopping.md",
+			ModTime: time.Date(2023, 5, 1, 0, 0, 0, 0, time.Local),
+			Body:    []byte("# Shopping\nmilk\n"),
+		}),
+	}
+
+	tests := []struct {
+		query  string
+		expect []bool
+	}{
+		{``, []bool{true, true, true}},
+		{`release`, []bool{true, true, false}},
+		{`tag:work AND title:"release" -draft after:2023-12-31`, []bool{true, false, false}},
+		{`tag:WORK -tag:draft`, []bool{true, false, false}},
+		{`body:milk OR body:v2.0`, []bool{false, true, true}},
+		{`NOT (tag:work OR name:shopping)`, []bool{false, false, false}},
+		{`notebook:HOME`, []bool{false, false, true}},
+		{`date:2024-03`, []bool{false, true, false}},
+		{`on:2023`, []bool{false, false, true}},
+		{`before:2024-01-01`, []bool{false, false, true}},
+		{`after:2024-01-01`, []bool{false, true, false}},
+		{`status:done`, []bool{true, false, false}},
+		{`title:"release plan"`, []bool{false, true, false}},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("%q: %v", test.query, err)
+		}
+		for i, d := range docs {
+			if got := q.Match(d); got != test.expect[i] {
+				t.Errorf("%q: want %v for %s but got %v", test.query, test.expect[i], d.Name, got)
+			}
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`title:"release`, `query: column 7: unterminated quoted string`},
+		{`tag:work AND`, `query: column 13: expected term but got end of query`},
+		{`OR foo`, `query: column 1: expected term but got OR`},
+		{`(foo OR bar`, `query: column 12: expected ")" for "(" at column 1 but got end of query`},
+		{`foo)`, `query: column 4: unexpected ")"`},
+		{`tag:`, `query: column 1: value for "tag" is empty`},
+		{`after:yesterday`, `query: column 1: invalid date "yesterday" for "after": use 2006-01-02, 2006-01 or 2006`},
+		{`:foo`, `query: column 1: field name is empty`},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.query)
+		if err == nil {
+			t.Errorf("%q: want error", test.query)
+			continue
+		}
+		if err.Error() != test.err {
+			t.Errorf("%q: want %q but got %q", test.query, test.err, err.Error())
+		}
+	}
+}
+
+func TestSetDefaultField(t *testing.T) {
+	d := NewDoc(&store.Memo{Name: "2024-01-01-note.md", Body: []byte("# Release note\n")})
+	q, err := Parse(`release OR title:foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Match(d) {
+		t.Fatalf("want %q to match the title", q)
+	}
+	q.SetDefaultField("name")
+	if q.Match(d) {
+		t.Fatalf("want %q not to match the title", q)
+	}
+}
+
+func TestCheckFields(t *testing.T) {
+	known := func(field string) bool { return field == "status" }
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`release tag:work status:done file:foo on:2024`, ``},
+		{`tga:work`, `query: column 1: unknown field "tga"`},
+		{`meeting -10:30`, `query: column 10: unknown field "10"`},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.query)
+		if err != nil {
+			t.Fatalf("%q: %v", test.query, err)
+		}
+		err = q.CheckFields(known)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%q: %v", test.query, err)
+			}
+		} else if err == nil || err.Error() != test.err {
+			t.Errorf("%q: want %q but got %v", test.query, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/query"
+	"github.com/mattn/memo/store"
+	"github.com/mattn/memo/wiki"
+)
+
+// renamePlan is the changes to rename the memo.
+type renamePlan struct {
+	Old    string
+	New    string
+	names  []string          // changed memos
+	before map[string][]byte // bodies before the change
+	after  map[string][]byte // bodies after the change
+}
+
+var (
+	inlineLinkReg = regexp.MustCompile(`(\]\()([^)\s]+)`)
+	refLinkReg    = regexp.MustCompile(`(?m)^([ \t]*\[[^\]\n]+\]:[ \t]*)(\S+)`)
+)
+
+// renamedName returns the new name of the memo keeping the notebook and the
+// date prefix.
+func renamedName(old, title string) (string, error) {
+	if escape(title) == "" {
+		return "", fmt.Errorf("invalid title: %q", title)
+	}
+	name := escape(title) + ".md"
+	if t, ok := store.DateFromName(old); ok {
+		name = t.Format("2006-01-02") + "-" + name
+	}
+	if nb := store.Notebook(old); nb != "" {
+		name = nb + "/" + name
+	}
+	return name, nil
+}
+
+// renameTarget returns the target of the wiki link to the renamed memo in
+// the same form as target.
+func renameTarget(target, oldName, oldTitle, newName, newTitle string) string {
+	if strings.EqualFold(target, oldTitle) {
+		return newTitle
+	}
+	t, ext := target, ""
+	if strings.HasSuffix(strings.ToLower(t), ".md") {
+		t, ext = t[:len(t)-3], t[len(t)-3:]
+	}
+	switch {
+	case strings.EqualFold(t, strings.TrimSuffix(oldName, ".md")):
+		return strings.TrimSuffix(newName, ".md") + ext
+	case strings.EqualFold(t, strings.TrimSuffix(path.Base(oldName), ".md")):
+		return strings.TrimSuffix(path.Base(newName), ".md") + ext
+	}
+	return escape(newTitle)
+}
+
+// renameLinks rewrites the wiki links and the markdown links to the renamed
+// memo in the body of the memo name.
+func renameLinks(b []byte, name string, r *wiki.Resolver, oldName, oldTitle, newName, newTitle string) []byte {
+	b = wiki.Replace(b, func(target, label string) (string, bool) {
+		if n, ok := r.Resolve(target); !ok || n != oldName {
+			return "", false
+		}
+		t := renameTarget(target, oldName, oldTitle, newName, newTitle)
+		if label == target {
+			return "[[" + t + "]]", true
+		}
+		return "[[" + t + "|" + label + "]]", true
+	})
+
+	base := &url.URL{Path: "/" + name}
+	rewrite := func(reg *regexp.Regexp, b []byte) []byte {
+		return reg.ReplaceAllFunc(b, func(m []byte) []byte {
+			sm := reg.FindSubmatch(m)
+			u, err := url.Parse(string(sm[2]))
+			if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+				return m
+			}
+			if base.ResolveReference(u).Path != "/"+oldName {
+				return m
+			}
+			p := "/" + newName
+			if !strings.HasPrefix(u.Path, "/") {
+				p = relativePath(path.Dir("/"+name), "/"+newName)
+			}
+			link := (&url.URL{Path: p, Fragment: u.Fragment, RawQuery: u.RawQuery}).String()
+			return append(append([]byte{}, sm[1]...), link...)
+		})
+	}
+	return rewrite(refLinkReg, rewrite(inlineLinkReg, b))
+}
+
+// relativePath returns the relative path from the directory to the file.
+// Both of them must be absolute slash-separated paths.
+func relativePath(dir, file string) string {
+	from := strings.Split(strings.Trim(dir, "/"), "/")
+	to := strings.Split(strings.Trim(file, "/"), "/")
+	if from[0] == "" {
+		from = nil
+	}
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	return strings.Repeat("../", len(from)-i) + strings.Join(to[i:], "/")
+}
+
+// planRename returns the changes to rename the memo old with title.
+func planRename(s store.Store, old, title string) (*renamePlan, error) {
+	newName, err := renamedName(old, title)
+	if err != nil {
+		return nil, err
+	}
+	if newName != old {
+		if _, err := s.Get(newName); err == nil {
+			return nil, fmt.Errorf("%s already exists", newName)
+		}
+	}
+	names, err := memoNames(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []wiki.Page
+	memos := map[string]*store.Memo{}
+	oldTitle := ""
+	for _, name := range names {
+		memo, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		memos[name] = memo
+		d := query.NewDoc(memo)
+		pages = append(pages, wiki.Page{Name: name, Title: d.Meta.Title})
+		if name == old {
+			oldTitle = d.Meta.Title
+		}
+	}
+	if _, ok := memos[old]; !ok {
+		return nil, fmt.Errorf("%s is not found", old)
+	}
+	r := wiki.NewResolver(pages)
+
+	plan := &renamePlan{
+		Old:    old,
+		New:    newName,
+		before: map[string][]byte{},
+		after:  map[string][]byte{},
+	}
+	for _, name := range names {
+		b := memos[name].Body
+		after := renameLinks(b, name, r, old, oldTitle, newName, title)
+		if name == old {
+			after, err = store.SetTitle(after, title)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		if name == old || string(after) != string(b) {
+			plan.names = append(plan.names, name)
+			plan.before[name] = b
+			plan.after[name] = after
+		}
+	}
+	return plan, nil
+}
+
+// apply renames the memo and updates the links.
+func (p *renamePlan) apply(s store.Store) error {
+	if p.New != p.Old {
+		if err := s.Create(p.New, p.after[p.Old]); err != nil {
+			return err
+		}
+		if err := s.Delete(p.Old); err != nil {
+			return err
+		}
+	} else if err := s.Update(p.Old, p.after[p.Old]); err != nil {
+		return err
+	}
+	for _, name := range p.names {
+		if name == p.Old {
+			continue
+		}
+		if err := s.Update(name, p.after[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diff returns the unified diff of the changes.
+func (p *renamePlan) diff() string {
+	var buf strings.Builder
+	for _, name := range p.names {
+		newName := name
+		if name == p.Old {
+			newName = p.New
+		}
+		buf.WriteString(unifiedDiff("a/"+name, "b/"+newName, string(p.before[name]), string(p.after[name])))
+	}
+	return buf.String()
+}
+
+// findMemo returns the memo specified with the file name or the query.
+func findMemo(s store.Store, arg string) (string, error) {
+	if store.IsMemo(arg) {
+		return arg, nil
+	}
+	q, err := query.Parse(arg)
+	if err != nil {
+		return "", err
+	}
+	docs, err := findMemos(s, q)
+	if err != nil {
+		return "", err
+	}
+	switch len(docs) {
+	case 0:
+		return "", errNoMatch
+	case 1:
+		return docs[0].Name, nil
+	}
+	return "", fmt.Errorf("%d memos matched with %q", len(docs), arg)
+}
+
+func cmdRename(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	if c.Args().Len() < 2 {
+		return cli.ShowSubcommandHelp(c)
+	}
+	s := cfg.memoStore()
+	old, err := findMemo(s, c.Args().First())
+	if err != nil {
+		return err
+	}
+	title := strings.Join(c.Args().Tail(), " ")
+	plan, err := planRename(s, old, title)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("dry-run") {
+		fmt.Printf("Rename: %s -> %s\n", plan.Old, plan.New)
+		fmt.Print(plan.diff())
+		return nil
+	}
+	if err := plan.apply(s); err != nil {
+		return err
+	}
+	if err := cfg.autoCommit(fmt.Sprintf("rename: %s -> %s", plan.Old, plan.New), append([]string{plan.Old, plan.New}, plan.names...)...); err != nil {
+		return err
+	}
+	fmt.Printf("Renamed: %s -> %s\n", plan.Old, plan.New)
+	for _, name := range plan.names {
+		if name != plan.Old {
+			fmt.Printf("Updated: %s\n", name)
+		}
+	}
+	return nil
+}
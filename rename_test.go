@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func newRenameTestStore() store.Store {
+	s := store.NewMemory()
+	s.Create("2017-01-01-old-title.md", []byte("# Old Title\n[self](2017-01-01-old-title.md)\n"))
+	s.Create("2017-01-02-a.md", []byte("# A\n[[Old Title]] [[old-title]] [[2017-01-01-old-title|label]] [x](2017-01-01-old-title.md#sec)\n"))
+	s.Create("work/2017-01-03-b.md", []byte("# B\n[y](../2017-01-01-old-title.md) [z](/2017-01-01-old-title.md)\n`[[old-title]]`\n\n[ref]: ../2017-01-01-old-title.md\n"))
+	s.Create("2017-01-04-c.md", []byte("# C\n[[other]]\n"))
+	return s
+}
+
+func TestCmdRename(t *testing.T) {
+	setupTestHome(t)
+	s := newRenameTestStore()
+
+	out := runTestApp(t, s, "rename", "2017-01-01-old-title.md", "New", "Title")
+	if !strings.Contains(out, "Renamed: 2017-01-01-old-title.md -> 2017-01-01-New-Title.md") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if _, err := s.Get("2017-01-01-old-title.md"); err == nil {
+		t.Fatal("old memo must be removed")
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"2017-01-01-New-Title.md", "# New Title\n[self](2017-01-01-New-Title.md)\n"},
+		{"2017-01-02-a.md", "# A\n[[New Title]] [[New-Title]] [[2017-01-01-New-Title|label]] [x](2017-01-01-New-Title.md#sec)\n"},
+		{"work/2017-01-03-b.md", "# B\n[y](../2017-01-01-New-Title.md) [z](/2017-01-01-New-Title.md)\n`[[old-title]]`\n\n[ref]: ../2017-01-01-New-Title.md\n"},
+		{"2017-01-04-c.md", "# C\n[[other]]\n"},
+	}
+	for _, test := range tests {
+		memo, err := s.Get(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(memo.Body); got != test.want {
+			t.Errorf("%s: want %q but got %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestCmdRenameDryRun(t *testing.T) {
+	setupTestHome(t)
+	s := newRenameTestStore()
+
+	out := runTestApp(t, s, "rename", "--dry-run", "2017-01-01-old-title.md", "New Title")
+	for _, want := range []string{
+		"--- a/2017-01-01-old-title.md\n+++ b/2017-01-01-New-Title.md\n",
+		"-# Old Title\n",
+		"+# New Title\n",
+		"--- a/2017-01-02-a.md\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in %q", want, out)
+		}
+	}
+	if _, err := s.Get("2017-01-01-old-title.md"); err != nil {
+		t.Fatal("memo must not be renamed with --dry-run")
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\neleven\n"
+	want := "--- a\n+++ b\n" +
+		"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+		"@@ -8,3 +8,4 @@\n 8\n 9\n 10\n+eleven\n"
+	if got := unifiedDiff("a", "b", a, b); got != want {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+	if got := unifiedDiff("a", "b", a, a); got != "" {
+		t.Fatalf("want empty diff but got %q", got)
+	}
+}
+
+func TestRenamedName(t *testing.T) {
+	tests := []struct {
+		old  string
+		want string
+	}{
+		{"2017-01-01-old-title.md", "2017-01-01-New-Title.md"},
+		{"2024-01-02.md", "2024-01-02-New-Title.md"},
+		{"work/2024-01-02.md", "work/2024-01-02-New-Title.md"},
+		{"work/old.md", "work/New-Title.md"},
+	}
+	for _, test := range tests {
+		got, err := renamedName(test.old, "New Title")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("%s: want %q but got %q", test.old, test.want, got)
+		}
+	}
+}
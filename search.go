@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/query"
+)
+
+const templateSearchContent = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>{{with .Query}}{{.}} - {{end}}Search</title>
+</head>
+<style>
+li {list-style-type: none; margin-bottom: 1em;}
+mark {background-color: #ff0;}
+.snippet {color: #555;}
+</style>
+<body>
+<form action="/search">
+  <input type="search" name="q" value="{{.Query}}" placeholder="Search" autofocus>
+  <select name="tag">
+    <option value="">all tags</option>{{range .Tags}}
+    <option{{if eq . $.Tag}} selected{{end}}>{{.}}</option>{{end}}
+  </select>
+  <input type="date" name="from" value="{{.From}}"> -
+  <input type="date" name="to" value="{{.To}}">
+  <button type="submit">Search</button>
+  <a href="/">Index</a>
+</form>
+{{with .Error}}<p>{{.}}</p>{{end}}
+{{if .Searched}}<p>{{len .Results}} memos</p>{{end}}
+<ul>{{range .Results}}
+  <li><a href="/{{.Name}}">{{.Body}}</a> <small>{{.Name}}</small>
+  <div class="snippet">{{.Snippet}}</div></li>{{end}}
+</ul>
+</body>
+</html>
+`
+
+type searchPage struct {
+	Query    string
+	Tag      string
+	From     string
+	To       string
+	Tags     []string
+	Searched bool
+	Results  []entry
+	Error    string
+}
+
+// highlight escapes s and marks the terms in s.
+func highlight(s string, terms []string) template.HTML {
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(term))
+		if err != nil {
+			continue
+		}
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			spans = append(spans, span{loc[0], loc[1]})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+
+	var buf strings.Builder
+	pos := 0
+	for i := 0; i < len(spans); i++ {
+		sp := spans[i]
+		if sp.end <= pos {
+			continue
+		}
+		if sp.start < pos {
+			sp.start = pos
+		}
+		// merge overlapped spans
+		for i+1 < len(spans) && spans[i+1].start <= sp.end {
+			i++
+			if spans[i].end > sp.end {
+				sp.end = spans[i].end
+			}
+		}
+		buf.WriteString(template.HTMLEscapeString(s[pos:sp.start]))
+		buf.WriteString("<mark>")
+		buf.WriteString(template.HTMLEscapeString(s[sp.start:sp.end]))
+		buf.WriteString("</mark>")
+		pos = sp.end
+	}
+	buf.WriteString(template.HTMLEscapeString(s[pos:]))
+	return template.HTML(buf.String())
+}
+
+// snippet returns the highlighted line around the first matched term in
+// content. width is the maximum width of the snippet.
+func snippet(content string, terms []string, width int) template.HTML {
+	lower := strings.ToLower(content)
+	first := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, strings.ToLower(term)); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	if first < 0 || len(lower) != len(content) {
+		// no term found, or the position cannot be mapped to content
+		first = 0
+	}
+
+	start := strings.LastIndexByte(content[:first], '\n') + 1
+	end := strings.IndexByte(content[first:], '\n')
+	if end < 0 {
+		end = len(content)
+	} else {
+		end += first
+	}
+	line := content[start:end]
+	before := []rune(content[start:first])
+
+	prefix := ""
+	if runewidth.StringWidth(line) > width && runewidth.StringWidth(string(before)) > width/3 {
+		// keep some context before the term
+		for runewidth.StringWidth(string(before)) > width/3 {
+			before = before[1:]
+		}
+		line = string(before) + content[first:end]
+		prefix = "..."
+	}
+	return template.HTML(template.HTMLEscapeString(prefix)) + highlight(runewidth.Truncate(line, width, "..."), terms)
+}
+
+func parseDateParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+func (srv *server) search(page *searchPage) ([]entry, error) {
+	from, err := parseDateParam(page.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseDateParam(page.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if strings.TrimSpace(page.Query) != "" {
+		if err := srv.cfg.updateIndex(srv.idx, srv.s); err != nil {
+			return nil, err
+		}
+		for _, result := range srv.idx.Search(page.Query) {
+			names = append(names, result.Name)
+		}
+	} else {
+		names, err = memoNames(srv.s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	terms := index.Tokenize(page.Query)
+	var entries []entry
+	for _, name := range names {
+		memo, err := srv.s.Get(name)
+		if err != nil {
+			continue
+		}
+		d := query.NewDoc(memo)
+		if page.Tag != "" && !d.Meta.HasTag(page.Tag) {
+			continue
+		}
+		date := d.Date()
+		if !from.IsZero() && date.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !date.Before(to.AddDate(0, 0, 1)) {
+			continue
+		}
+		entries = append(entries, entry{
+			Name:    name,
+			Body:    highlight(runewidth.Truncate(d.Meta.Title, 80, "..."), terms),
+			Meta:    d.Meta,
+			Snippet: snippet(string(d.Content), terms, 160),
+		})
+	}
+	return entries, nil
+}
+
+func (srv *server) handleSearch(w http.ResponseWriter, req *http.Request) {
+	page := &searchPage{
+		Query: req.FormValue("q"),
+		Tag:   req.FormValue("tag"),
+		From:  req.FormValue("from"),
+		To:    req.FormValue("to"),
+	}
+	page.Searched = page.Query != "" || page.Tag != "" || page.From != "" || page.To != ""
+
+	status := http.StatusOK
+	if page.Searched {
+		entries, err := srv.search(page)
+		if err != nil {
+			page.Error = err.Error()
+			status = http.StatusBadRequest
+		}
+		page.Results = entries
+	}
+
+	if srv.cfg.TemplateDirFile != "" {
+		if page.Error != "" {
+			srv.renderError(w, status, errors.New(page.Error))
+			return
+		}
+		srv.serveDir(w, page.Results)
+		return
+	}
+
+	counts, err := tagCounts(srv.s)
+	if err != nil {
+		srv.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for tag := range counts {
+		page.Tags = append(page.Tags, tag)
+	}
+	sort.Strings(page.Tags)
+
+	srv.render(w, status, "search", page, nil)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		s     string
+		terms []string
+		want  template.HTML
+	}{
+		{"hello world", []string{"world"}, "hello <mark>world</mark>"},
+		{"Hello <b>", []string{"hello"}, "<mark>Hello</mark> &lt;b&gt;"},
+		{"東京都に行く", []string{"東京", "京都"}, "<mark>東京都</mark>に行く"},
+		{"foo bar foo", []string{"foo", "bar"}, "<mark>foo</mark> <mark>bar</mark> <mark>foo</mark>"},
+		{"nothing", nil, "nothing"},
+	}
+	for _, test := range tests {
+		if got := highlight(test.s, test.terms); got != test.want {
+			t.Errorf("highlight(%q, %q): want %q but got %q", test.s, test.terms, test.want, got)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	content := "# Title\nfirst line\n" + strings.Repeat("x", 100) + " needle " + strings.Repeat("y", 100) + "\n"
+	got := string(snippet(content, []string{"needle"}, 80))
+	if !strings.HasPrefix(got, "...") || !strings.Contains(got, "<mark>needle</mark>") {
+		t.Fatalf("unexpected snippet %q", got)
+	}
+	if got := snippet(content, []string{"nothing"}, 80); got != "# Title" {
+		t.Fatalf("want first line but got %q", got)
+	}
+}
+
+func TestHandleSearch(t *testing.T) {
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntags: [work]\n---\n# Foo\nhello world\n"))
+	s.Create("2017-02-01-bar.md", []byte("# Bar\nhello memo\n"))
+	s.Create("2017-03-01-baz.md", []byte("---\ntags: [work]\n---\n# Baz\ngood bye\n"))
+	ts := newTestServer(t, s)
+
+	search := func(params string) string {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/search?" + params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	tests := []struct {
+		params string
+		want   []string
+	}{
+		{"q=hello", []string{"2017-01-01-foo.md", "2017-02-01-bar.md"}},
+		{"q=hello&tag=work", []string{"2017-01-01-foo.md"}},
+		{"tag=work&from=2017-02-01", []string{"2017-03-01-baz.md"}},
+		{"from=2017-01-15&to=2017-02-01", []string{"2017-02-01-bar.md"}},
+	}
+	all := []string{"2017-01-01-foo.md", "2017-02-01-bar.md", "2017-03-01-baz.md"}
+	for _, test := range tests {
+		got := search(test.params)
+		for _, name := range all {
+			want := false
+			for _, w := range test.want {
+				want = want || w == name
+			}
+			if strings.Contains(got, `href="/`+name+`"`) != want {
+				t.Errorf("%s: want %s in results %v", test.params, name, want)
+			}
+		}
+	}
+	if got := search("q=hello"); !strings.Contains(got, "<mark>hello</mark>") {
+		t.Errorf("want highlighted snippet but got %s", got)
+	}
+}
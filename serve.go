@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/pkg/browser"
+	"github.com/shurcooL/github_flavored_markdown"
+	"github.com/shurcooL/github_flavored_markdown/gfmstyle"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/store"
+)
+
+type server struct {
+	cfg *config
+	s   store.Store
+	idx *index.Index
+
+	// mu serializes writes to the memos.
+	mu sync.Mutex
+	// localOnly rejects the requests for the host names other than localhost
+	// against DNS rebinding, since anyone can access the memos.
+	localOnly bool
+
+	events events
+	docs   docCache
+	tmpl   atomic.Pointer[templateSet]
+}
+
+func newServer(cfg *config) (*server, error) {
+	s := cfg.memoStore()
+	idx, err := cfg.openIndex(s)
+	if err != nil {
+		return nil, err
+	}
+	srv := &server{cfg: cfg, s: s, idx: idx}
+	if _, err := srv.templates(); err != nil {
+		return nil, err
+	}
+	return srv, nil
+}
+
+func (srv *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", srv.handleSearch)
+	mux.HandleFunc("/tags/", srv.handleTags)
+	mux.HandleFunc("/edit/", srv.handleEdit)
+	mux.HandleFunc("/new", srv.handleNew)
+	mux.HandleFunc("GET /events", srv.handleEvents)
+	mux.HandleFunc("GET /feed.xml", srv.handleFeed)
+	mux.HandleFunc("GET /rss.xml", srv.handleFeed)
+	mux.HandleFunc("GET /graph", srv.handleGraph)
+	mux.HandleFunc("GET /graph.json", srv.handleGraphJSON)
+	mux.HandleFunc("/", srv.handleMemo)
+	mux.Handle("/assets/gfm/", http.StripPrefix("/assets/gfm", http.FileServer(gfmstyle.Assets)))
+	mux.Handle("/assets/", http.StripPrefix("/assets", http.FileServer(http.Dir(srv.cfg.AssetsDir))))
+	srv.registerAPI(mux)
+	return srv.guard(mux)
+}
+
+func (srv *server) serveDir(w http.ResponseWriter, entries []entry) {
+	srv.render(w, http.StatusOK, "dir", entries, nil)
+}
+
+// dirEntries returns the entries of the memos for the directory page.
+func dirEntries(s store.Store, names []string) []entry {
+	var entries []entry
+	for _, name := range names {
+		meta := memoMeta(s, name)
+		entries = append(entries, entry{
+			Name: name,
+			Body: template.HTML(template.HTMLEscapeString(runewidth.Truncate(meta.Title, 80, "..."))),
+			Meta: meta,
+		})
+	}
+	return entries
+}
+
+// memoEntries returns the entries of memos having all of tags.
+func (srv *server) memoEntries(tags ...string) ([]entry, error) {
+	files, err := memoNames(srv.s)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, file := range files {
+		if hasTags(memoMeta(srv.s, file), tags) {
+			names = append(names, file)
+		}
+	}
+	return dirEntries(srv.s, names), nil
+}
+
+// tagEntries returns the entries of tags for the directory page.
+func tagEntries(counts map[string]int) []entry {
+	var tags []entry
+	for tag, count := range counts {
+		tags = append(tags, entry{
+			Name: "tags/" + url.PathEscape(tag),
+			Body: template.HTML(template.HTMLEscapeString(fmt.Sprintf("%s (%d)", tag, count))),
+			Meta: &store.Meta{Title: tag},
+		})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
+
+func (srv *server) handleTags(w http.ResponseWriter, req *http.Request) {
+	tag := strings.TrimPrefix(req.URL.Path, "/tags/")
+	if tag != "" {
+		entries, err := srv.memoEntries(tag)
+		if err != nil {
+			srv.renderError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(entries) == 0 {
+			srv.renderError(w, http.StatusNotFound, nil)
+			return
+		}
+		srv.serveDir(w, entries)
+		return
+	}
+
+	counts, err := tagCounts(srv.s)
+	if err != nil {
+		srv.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	srv.serveDir(w, tagEntries(counts))
+}
+
+// memoEntry returns the entry of the memo rendered as HTML. Wiki links are
+// rendered as the links if g is not nil.
+func memoEntry(memo *store.Memo, g *linkGraph) entry {
+	meta, content := store.ParseMeta(memo.Body)
+	e := entry{
+		Name: "/" + memo.Name,
+		Meta: meta,
+	}
+	if g != nil {
+		content = wikiMarkdown(content, g.resolver)
+	}
+	e.Body = template.HTML(github_flavored_markdown.Markdown(content))
+	return e
+}
+
+// linkGraph returns the wiki links between memos.
+func (srv *server) linkGraph() (*linkGraph, error) {
+	docs, links, err := srv.loadDocs()
+	if err != nil {
+		return nil, err
+	}
+	return newLinkGraph(docs, links), nil
+}
+
+func (srv *server) handleMemo(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/" {
+		page, entries, err := srv.dirPageOf(req)
+		if err != nil {
+			srv.renderError(w, http.StatusInternalServerError, err)
+			return
+		}
+		srv.render(w, http.StatusOK, "dir", entries, page)
+		return
+	}
+
+	// files other than memos, like .git/config, are not served.
+	name := escapePath(req.URL.Path)
+	if !store.ValidName(name) {
+		srv.renderError(w, http.StatusNotFound, nil)
+		return
+	}
+	memo, err := srv.s.Get(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			srv.renderError(w, http.StatusNotFound, nil)
+		} else {
+			srv.renderError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	g, err := srv.linkGraph()
+	if err != nil {
+		srv.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	e := memoEntry(memo, g)
+	e.Name = req.URL.Path
+	e.Backlinks = dirEntries(srv.s, g.backlinks[memo.Name])
+	srv.render(w, http.StatusOK, "body", e, nil)
+}
+
+func cmdServe(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("readonly") {
+		cfg.Serve.ReadOnly = true
+	}
+	if c.IsSet("tls-cert") {
+		cfg.Serve.TLSCert = c.String("tls-cert")
+	}
+	if c.IsSet("tls-key") {
+		cfg.Serve.TLSKey = c.String("tls-key")
+	}
+	certFile, keyFile := expandPath(cfg.Serve.TLSCert), expandPath(cfg.Serve.TLSKey)
+	if c.Bool("tls-self-signed") {
+		if certFile != "" || keyFile != "" {
+			return errors.New("--tls-self-signed cannot be used with the certificate")
+		}
+		certFile, keyFile, err = selfSignedCert()
+		if err != nil {
+			return err
+		}
+	} else if (certFile == "") != (keyFile == "") {
+		return errors.New("both of the certificate and the key are required for TLS")
+	}
+
+	srv, err := newServer(&cfg)
+	if err != nil {
+		return err
+	}
+
+	addr := c.String("addr")
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if !cfg.Serve.authEnabled() {
+		if isLoopback(host) {
+			srv.localOnly = true
+		} else {
+			log.Printf("warning: serving memos on %s without authentication", addr)
+		}
+	}
+	scheme := "http"
+	if certFile != "" {
+		scheme = "https"
+	}
+	var url string
+	if host == "" {
+		url = scheme + "://localhost" + addr
+	} else {
+		url = scheme + "://" + addr
+	}
+	go srv.watch(time.Second)
+	go srv.watchTemplates(time.Second)
+	if !c.Bool("no-browser") {
+		browser.OpenURL(url)
+	}
+	if certFile != "" {
+		return http.ListenAndServeTLS(addr, certFile, keyFile, srv.handler())
+	}
+	return http.ListenAndServe(addr, srv.handler())
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
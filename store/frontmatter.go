@@ -0,0 +1,239 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Meta is the metadata of a memo read from its front matter.
+type Meta struct {
+	Title      string
+	Date       time.Time
+	Tags       []string
+	Categories []string
+	// Extra holds the keys other than title, date, tags and categories.
+	Extra map[string]interface{}
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %q", s)
+}
+
+// splitFrontMatter returns the front matter and the content of b. The format
+// is "yaml" for "---" and "toml" for "+++", or empty if b has no front matter.
+func splitFrontMatter(b []byte) (format string, fm, content []byte, err error) {
+	var delim string
+	switch {
+	case bytes.HasPrefix(b, []byte("---\n")), bytes.HasPrefix(b, []byte("---\r\n")):
+		format, delim = "yaml", "---"
+	case bytes.HasPrefix(b, []byte("+++\n")), bytes.HasPrefix(b, []byte("+++\r\n")):
+		format, delim = "toml", "+++"
+	default:
+		return "", nil, b, nil
+	}
+	pos := bytes.IndexByte(b, '\n') + 1
+	for start := pos; start < len(b); {
+		end := bytes.IndexByte(b[start:], '\n')
+		next := len(b)
+		if end >= 0 {
+			end += start
+			next = end + 1
+		} else {
+			end = len(b)
+		}
+		if strings.TrimRight(string(b[start:end]), "\r") == delim {
+			return format, b[pos:start], b[next:], nil
+		}
+		start = next
+	}
+	return "", nil, b, fmt.Errorf("%s front matter is not closed", format)
+}
+
+// ParseFrontMatter parses the YAML ("---") or TOML ("+++") front matter at
+// the head of b, and returns the metadata and the content following it. When
+// the title is not given, the first line of the content is used instead.
+// The content is returned even if the front matter is invalid.
+func ParseFrontMatter(b []byte) (*Meta, []byte, error) {
+	format, fm, content, err := splitFrontMatter(b)
+	if err != nil {
+		return nil, b, err
+	}
+	values := map[string]interface{}{}
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(fm, &values)
+	case "toml":
+		err = toml.Unmarshal(fm, &values)
+	}
+	if err != nil {
+		return nil, content, fmt.Errorf("invalid %s front matter: %v", format, err)
+	}
+
+	meta := &Meta{Extra: map[string]interface{}{}}
+	for k, v := range values {
+		switch strings.ToLower(k) {
+		case "title":
+			// empty title falls back to the first heading.
+			if v != nil {
+				meta.Title = strings.TrimSpace(fmt.Sprint(v))
+			}
+		case "date":
+			switch t := v.(type) {
+			case nil:
+			case time.Time:
+				meta.Date = t
+			default:
+				meta.Date, err = parseDate(fmt.Sprint(v))
+				if err != nil {
+					return nil, content, err
+				}
+			}
+		case "tags":
+			meta.Tags = stringList(v)
+		case "categories":
+			meta.Categories = stringList(v)
+		default:
+			meta.Extra[k] = v
+		}
+	}
+	if meta.Title == "" {
+		meta.Title = Title(content)
+	}
+	return meta, content, nil
+}
+
+// ParseMeta is like ParseFrontMatter, but the invalid front matter is
+// ignored and the title is taken from the content.
+func ParseMeta(b []byte) (*Meta, []byte) {
+	meta, content, err := ParseFrontMatter(b)
+	if err != nil {
+		meta = &Meta{Title: Title(content)}
+	}
+	return meta, content
+}
+
+// Title returns the first line of b without leading "#".
+func Title(b []byte) string {
+	line := strings.SplitN(strings.TrimSpace(string(b)), "\n", 2)[0]
+	return strings.TrimSpace(strings.TrimLeft(line, "# "))
+}
+
+func stringList(v interface{}) []string {
+	var list []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			list = append(list, stringList(e)...)
+		}
+	case []string:
+		for _, e := range t {
+			list = append(list, stringList(e)...)
+		}
+	case nil:
+	default:
+		for _, s := range strings.Split(fmt.Sprint(t), ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// HasTag reports whether the memo is tagged with tag.
+func (m *Meta) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+var nameDateReg = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})`)
+
+// DateFromName returns the date of the "2006-01-02-" prefix of the memo name.
+func DateFromName(name string) (time.Time, bool) {
+	m := nameDateReg.FindStringSubmatch(path.Base(name))
+	if m == nil {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation("2006-01-02", m[1], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+var (
+	yamlTitleReg = regexp.MustCompile(`(?m)^title[ \t]*:[^\r\n]*`)
+	tomlTitleReg = regexp.MustCompile(`(?m)^title[ \t]*=[^\r\n]*`)
+	setextReg    = regexp.MustCompile(`^=+[ \t]*\r?$`)
+)
+
+// SetTitle returns b with the title replaced. The title in the front matter
+// is replaced, and the heading at the head of the content is replaced if it
+// is the title.
+func SetTitle(b []byte, title string) ([]byte, error) {
+	meta, _, err := ParseFrontMatter(b)
+	if err != nil {
+		return nil, err
+	}
+	format, _, content, err := splitFrontMatter(b)
+	if err != nil {
+		return nil, err
+	}
+	head := b[:len(b)-len(content)]
+	hasTitle := false
+	switch format {
+	case "yaml":
+		hasTitle = yamlTitleReg.Match(head)
+		head = yamlTitleReg.ReplaceAllLiteral(head, []byte("title: "+strconv.Quote(title)))
+	case "toml":
+		hasTitle = tomlTitleReg.Match(head)
+		head = tomlTitleReg.ReplaceAllLiteral(head, []byte("title = "+strconv.Quote(title)))
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		text := strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if hasTitle && Title([]byte(text)) != meta.Title {
+			break
+		}
+		eol := line[len(text):]
+		if strings.HasPrefix(text, "#") {
+			level := len(text) - len(strings.TrimLeft(text, "#"))
+			lines[i] = strings.Repeat("#", level) + " " + title + eol
+		} else if i+1 < len(lines) && setextReg.MatchString(strings.TrimRight(lines[i+1], "\n")) {
+			lines[i] = title + eol
+		}
+		break
+	}
+	return append(append([]byte{}, head...), strings.Join(lines, "")...), nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		input   string
+		title   string
+		date    time.Time
+		tags    []string
+		extra   map[string]interface{}
+		content string
+	}{
+		{
+			input:   "# foo\nbar\n",
+			title:   "foo",
+			extra:   map[string]interface{}{},
+			content: "# foo\nbar\n",
+		},
+		{
+			input:   "---\ntitle: foo\ndate: 2017-02-07 10:20\ntags: [work, meeting]\ndraft: true\n---\n# bar\n",
+			title:   "foo",
+			date:    time.Date(2017, 2, 7, 10, 20, 0, 0, time.Local),
+			tags:    []string{"work", "meeting"},
+			extra:   map[string]interface{}{"draft": true},
+			content: "# bar\n",
+		},
+		{
+			input:   "+++\ndate = \"2017-02-07\"\ntags = \"work, meeting\"\n+++\n\n# bar\n",
+			title:   "bar",
+			date:    time.Date(2017, 2, 7, 0, 0, 0, 0, time.Local),
+			tags:    []string{"work", "meeting"},
+			extra:   map[string]interface{}{},
+			content: "\n# bar\n",
+		},
+		{
+			input:   "---\ntitle:\ndate:\n---\n# bar\n",
+			title:   "bar",
+			extra:   map[string]interface{}{},
+			content: "# bar\n",
+		},
+		{
+			input:   "+++\ntitle = \"\"\n+++\n# bar\n",
+			title:   "bar",
+			extra:   map[string]interface{}{},
+			content: "# bar\n",
+		},
+		{
+			input:   "---\r\ntitle: foo\r\n---\r\nbar\r\n",
+			title:   "foo",
+			extra:   map[string]interface{}{},
+			content: "bar\r\n",
+		},
+	}
+	for _, test := range tests {
+		meta, content, err := ParseFrontMatter([]byte(test.input))
+		if err != nil {
+			t.Fatalf("%q: %v", test.input, err)
+		}
+		if meta.Title != test.title {
+			t.Errorf("%q: want title %q but got %q", test.input, test.title, meta.Title)
+		}
+		if !meta.Date.Equal(test.date) {
+			t.Errorf("%q: want date %v but got %v", test.input, test.date, meta.Date)
+		}
+		if !reflect.DeepEqual(meta.Tags, test.tags) {
+			t.Errorf("%q: want tags %v but got %v", test.input, test.tags, meta.Tags)
+		}
+		if !reflect.DeepEqual(meta.Extra, test.extra) {
+			t.Errorf("%q: want extra %v but got %v", test.input, test.extra, meta.Extra)
+		}
+		if string(content) != test.content {
+			t.Errorf("%q: want content %q but got %q", test.input, test.content, string(content))
+		}
+	}
+}
+
+func TestParseFrontMatterError(t *testing.T) {
+	for _, input := range []string{
+		"---\ntitle: foo\n",
+		"---\ntitle: [foo\n---\n",
+		"+++\ntitle = \n+++\n",
+		"---\ndate: tomorrow\n---\n",
+	} {
+		if _, _, err := ParseFrontMatter([]byte(input)); err == nil {
+			t.Errorf("%q: want error", input)
+		}
+	}
+}
+
+func TestParseMeta(t *testing.T) {
+	tests := []struct {
+		input   string
+		title   string
+		content string
+	}{
+		{"---\ntitle: Release: v2\n---\n# Release\nbody\n", "Release", "# Release\nbody\n"},
+		{"---\ndate: someday\n---\n# Someday\n", "Someday", "# Someday\n"},
+		{"+++\ntitle = \n+++\nfoo\n", "foo", "foo\n"},
+	}
+	for _, test := range tests {
+		meta, content := ParseMeta([]byte(test.input))
+		if meta.Title != test.title {
+			t.Errorf("%q: want title %q but got %q", test.input, test.title, meta.Title)
+		}
+		if string(content) != test.content {
+			t.Errorf("%q: want content %q but got %q", test.input, test.content, content)
+		}
+	}
+}
+
+func TestSetTitle(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"# Old\nbody\n", "# New: Title\nbody\n"},
+		{"\n## Old\r\nbody\r\n", "\n## New: Title\r\nbody\r\n"},
+		{"Old\n===\nbody\n", "New: Title\n===\nbody\n"},
+		{"plain text\n", "plain text\n"},
+		{"---\ntitle: Old\ntags: [a]\n---\n# Old\n", "---\ntitle: \"New: Title\"\ntags: [a]\n---\n# New: Title\n"},
+		{"---\ntitle: Old\n---\n# Heading\n", "---\ntitle: \"New: Title\"\n---\n# Heading\n"},
+		{"---\ntags: [a]\n---\n# Old\n", "---\ntags: [a]\n---\n# New: Title\n"},
+		{"+++\ntitle = \"Old\"\n+++\n# Old\n", "+++\ntitle = \"New: Title\"\n+++\n# New: Title\n"},
+	}
+	for _, test := range tests {
+		got, err := SetTitle([]byte(test.input), "New: Title")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != test.want {
+			t.Errorf("SetTitle(%q): want %q but got %q", test.input, test.want, got)
+		}
+		meta, _, err := ParseFrontMatter(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.input != "plain text\n" && meta.Title != "New: Title" {
+			t.Errorf("SetTitle(%q): title is %q", test.input, meta.Title)
+		}
+	}
+	if _, err := SetTitle([]byte("---\ntitle: [\n---\n"), "x"); err == nil {
+		t.Fatal("want error for invalid front matter")
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FS is a Store backed by a directory. Subdirectories are notebooks.
+type FS struct {
+	Dir string
+}
+
+// NewFS returns a Store for the directory.
+func NewFS(dir string) *FS {
+	return &FS{Dir: dir}
+}
+
+// Path returns the file path of the memo.
+func (s *FS) Path(name string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(name))
+}
+
+// List implements Store. Files and directories starting with "." are
+// skipped.
+func (s *FS) List() ([]*Memo, error) {
+	var memos []*Memo
+	err := fs.WalkDir(os.DirFS(s.Dir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != "." && strings.HasPrefix(d.Name(), ".") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !ValidName(p) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		memos = append(memos, &Memo{
+			Name:    p,
+			ModTime: fi.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortMemos(memos)
+	return memos, nil
+}
+
+// Get implements Store.
+func (s *FS) Get(name string) (*Memo, error) {
+	if err := checkName("open", name); err != nil {
+		return nil, err
+	}
+	p := s.Path(name)
+	fi, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	return &Memo{
+		Name:    name,
+		ModTime: fi.ModTime(),
+		Body:    b,
+	}, nil
+}
+
+// Create implements Store. The directory of the notebook is created if it
+// does not exist.
+func (s *FS) Create(name string, body []byte) error {
+	if err := checkName("open", name); err != nil {
+		return err
+	}
+	if dir := path.Dir(name); dir != "." {
+		if err := os.MkdirAll(s.Path(dir), 0700); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.Path(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// Update implements Store.
+func (s *FS) Update(name string, body []byte) error {
+	if err := checkName("open", name); err != nil {
+		return err
+	}
+	p := s.Path(name)
+	if _, err := os.Stat(p); err != nil {
+		return err
+	}
+	return os.WriteFile(p, body, 0644)
+}
+
+// Delete implements Store.
+func (s *FS) Delete(name string) error {
+	if err := checkName("remove", name); err != nil {
+		return err
+	}
+	return os.Remove(s.Path(name))
+}
+
+// Search implements Store.
+func (s *FS) Search(re *regexp.Regexp) ([]*Match, error) {
+	memos, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Match
+	for _, memo := range memos {
+		b, err := os.ReadFile(s.Path(memo.Name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		matches = append(matches, searchBody(memo.Name, b, re)...)
+	}
+	return matches, nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"io/fs"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Memory is a Store kept in memory. It is useful for testing.
+type Memory struct {
+	mu    sync.Mutex
+	memos map[string]*Memo
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{memos: map[string]*Memo{}}
+}
+
+// List implements Store.
+func (s *Memory) List() ([]*Memo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var memos []*Memo
+	for _, m := range s.memos {
+		memos = append(memos, &Memo{Name: m.Name, ModTime: m.ModTime})
+	}
+	sortMemos(memos)
+	return memos, nil
+}
+
+// Get implements Store.
+func (s *Memory) Get(name string) (*Memo, error) {
+	if err := checkName("open", name); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.memos[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &Memo{Name: m.Name, ModTime: m.ModTime, Body: append([]byte(nil), m.Body...)}, nil
+}
+
+// Create implements Store.
+func (s *Memory) Create(name string, body []byte) error {
+	if err := checkName("open", name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.memos[name]; ok {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	}
+	s.memos[name] = &Memo{Name: name, ModTime: time.Now(), Body: append([]byte(nil), body...)}
+	return nil
+}
+
+// Update implements Store.
+func (s *Memory) Update(name string, body []byte) error {
+	if err := checkName("open", name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.memos[name]
+	if !ok {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	m.Body = append([]byte(nil), body...)
+	m.ModTime = time.Now()
+	return nil
+}
+
+// Delete implements Store.
+func (s *Memory) Delete(name string) error {
+	if err := checkName("remove", name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.memos[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(s.memos, name)
+	return nil
+}
+
+// Search implements Store.
+func (s *Memory) Search(re *regexp.Regexp) ([]*Match, error) {
+	memos, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Match
+	for _, memo := range memos {
+		s.mu.Lock()
+		m, ok := s.memos[memo.Name]
+		s.mu.Unlock()
+		if ok {
+			matches = append(matches, searchBody(m.Name, m.Body, re)...)
+		}
+	}
+	return matches, nil
+}
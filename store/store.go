@@ -0,0 +1,103 @@
+// Package store provides access to the memos kept in a memo directory.
+package store
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Memo is a single memo in a Store.
+type Memo struct {
+	Name    string
+	ModTime time.Time
+	Body    []byte
+}
+
+// Match is a line of a memo matched by Search.
+type Match struct {
+	Name string
+	Line int
+	Text string
+}
+
+// Store is the storage of memos. Names are slash-separated paths relative to
+// the store root, and the directory part of the name is the notebook.
+type Store interface {
+	// List returns the memos in the store without the body, newest name first.
+	List() ([]*Memo, error)
+	// Get returns the memo with the body.
+	Get(name string) (*Memo, error)
+	// Create adds a new memo. It fails if the memo already exists.
+	Create(name string, body []byte) error
+	// Update replaces the body of the memo.
+	Update(name string, body []byte) error
+	// Delete removes the memo.
+	Delete(name string) error
+	// Search returns the lines matched with re.
+	Search(re *regexp.Regexp) ([]*Match, error)
+}
+
+// IsMemo reports whether the name is a memo file.
+func IsMemo(name string) bool {
+	return strings.HasSuffix(name, ".md")
+}
+
+// ValidName reports whether the name can be a memo in the store. Memos are
+// not in the files and directories starting with ".", like ".git".
+func ValidName(name string) bool {
+	if !fs.ValidPath(name) || !IsMemo(name) {
+		return false
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// Notebook returns the notebook of the memo, or empty for the memo at the
+// root of the store.
+func Notebook(name string) string {
+	if dir := path.Dir(name); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+func checkName(op, name string) error {
+	if !ValidName(name) {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+// sortMemos sorts memos by the file name, newest first, regardless of
+// notebooks.
+func sortMemos(memos []*Memo) {
+	sort.Slice(memos, func(i, j int) bool {
+		bi, bj := path.Base(memos[i].Name), path.Base(memos[j].Name)
+		if bi != bj {
+			return bi > bj
+		}
+		return memos[i].Name > memos[j].Name
+	})
+}
+
+func searchBody(name string, body []byte, re *regexp.Regexp) []*Match {
+	var matches []*Match
+	for i, line := range strings.Split(string(body), "\n") {
+		if re.MatchString(line) {
+			matches = append(matches, &Match{
+				Name: name,
+				Line: i + 1,
+				Text: line,
+			})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+
+	if err := s.Create("2017-01-01-foo.md", []byte("# foo\nhello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create("2017-01-02-bar.md", []byte("# bar\nworld\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create("2017-01-02-bar.md", nil); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("want ErrExist but got %v", err)
+	}
+
+	memos, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(memos) != 2 || memos[0].Name != "2017-01-02-bar.md" || memos[1].Name != "2017-01-01-foo.md" {
+		t.Fatalf("unexpected list: %v", memos)
+	}
+
+	if err := s.Update("2017-01-01-foo.md", []byte("# foo\nhello world\n")); err != nil {
+		t.Fatal(err)
+	}
+	memo, err := s.Get("2017-01-01-foo.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(memo.Body); got != "# foo\nhello world\n" {
+		t.Fatalf("want updated body but got %q", got)
+	}
+
+	matches, err := s.Search(regexp.MustCompile(`world`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("want 2 matches but got %d", len(matches))
+	}
+	if matches[1].Name != "2017-01-01-foo.md" || matches[1].Line != 2 {
+		t.Fatalf("unexpected match: %+v", matches[1])
+	}
+
+	for _, name := range []string{".git/config", ".2017-01-03-baz.md", "2017-01-03-baz.txt"} {
+		if err := s.Create(name, nil); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("want ErrInvalid for %s but got %v", name, err)
+		}
+		if _, err := s.Get(name); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("want ErrInvalid for %s but got %v", name, err)
+		}
+		if err := s.Update(name, nil); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("want ErrInvalid for %s but got %v", name, err)
+		}
+		if err := s.Delete(name); !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("want ErrInvalid for %s but got %v", name, err)
+		}
+	}
+
+	if err := s.Create("work/2017-01-03-baz.md", []byte("# baz\n")); err != nil {
+		t.Fatal(err)
+	}
+	memos, err = s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(memos) != 3 || memos[0].Name != "work/2017-01-03-baz.md" {
+		t.Fatalf("unexpected list: %v", memos)
+	}
+	if nb := Notebook(memos[0].Name); nb != "work" {
+		t.Fatalf("want notebook work but got %q", nb)
+	}
+	if _, err := s.Get("../2017-01-01-foo.md"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("want ErrInvalid but got %v", err)
+	}
+
+	if err := s.Delete("2017-01-02-bar.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("2017-01-02-bar.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("want ErrNotExist but got %v", err)
+	}
+	if err := s.Update("2017-01-02-bar.md", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("want ErrNotExist but got %v", err)
+	}
+}
+
+func TestFS(t *testing.T) {
+	dir := t.TempDir()
+	// hidden files are not listed.
+	if err := os.WriteFile(filepath.Join(dir, ".2017-01-03-baz.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, NewFS(dir))
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+const templateErrorContent = `
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="UTF-8">
+  <title>{{.Status}} {{.StatusText}}</title>
+</head>
+<style>
+body {font-family: sans-serif; margin: 3em;}
+h1 {color: #c00;}
+pre {background-color: #f6f8fa; padding: 1em; white-space: pre-wrap;}
+</style>
+<body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+{{with .Message}}<pre>{{.}}</pre>{{end}}
+<p><a href="/">Back to index</a></p>
+</body>
+</html>
+`
+
+// defaultTemplates are the names and the contents of the builtin templates.
+// They can be overridden with <name>.html in the templates directory.
+var defaultTemplates = map[string]string{
+	"dir":    templateDirContent,
+	"body":   templateBodyContent,
+	"search": templateSearchContent,
+	"edit":   templateEditContent,
+	"new":    templateNewContent,
+	"graph":  templateGraphContent,
+	"error":  templateErrorContent,
+}
+
+type errorPage struct {
+	Status     int
+	StatusText string
+	Message    string
+}
+
+// templateFuncs returns the functions for the templates. static reports
+// whether the pages are exported as the static site, and page is the paging
+// of the index page.
+func templateFuncs(static bool, page *dirPage) template.FuncMap {
+	return template.FuncMap{
+		"static": func() bool { return static },
+		"page":   func() *dirPage { return page },
+		"date": func(t time.Time, layout ...string) string {
+			if t.IsZero() {
+				return ""
+			}
+			if len(layout) > 0 {
+				return t.Format(layout[0])
+			}
+			return t.Format("2006-01-02")
+		},
+		"truncate": func(s string, width int) string {
+			return runewidth.Truncate(s, width, "...")
+		},
+		"tagLinks": func(tags []string) template.HTML {
+			var links []string
+			for _, tag := range tags {
+				u := "/tags/" + (&url.URL{Path: tag}).EscapedPath()
+				links = append(links, fmt.Sprintf(`<a href="%s">#%s</a>`, template.HTMLEscapeString(u), template.HTMLEscapeString(tag)))
+			}
+			return template.HTML(strings.Join(links, " "))
+		},
+	}
+}
+
+// templateSet is the parsed templates for serve and export.
+type templateSet struct {
+	templates map[string]*template.Template
+	files     map[string]time.Time // watched files and the modification times
+}
+
+// templateFiles returns the files to watch for the templates.
+func (cfg *config) templateFiles() []string {
+	var files []string
+	for _, file := range []string{cfg.TemplateDirFile, cfg.TemplateBodyFile} {
+		if file = expandPath(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	if dir := expandPath(cfg.TemplateDir); dir != "" {
+		// the directory is watched for added or removed files
+		files = append(files, dir)
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.html"))
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// loadTemplates parses the templates. <name>.html in the templates directory
+// overrides the builtin template, and the other files in the directory are
+// layouts and partials which can be used from all of the templates. Each
+// template is parsed separately, so they can define the same blocks.
+// templatedirfile and templatebodyfile take precedence over dir.html and
+// body.html.
+func (cfg *config) loadTemplates() (*templateSet, error) {
+	ts := &templateSet{
+		templates: map[string]*template.Template{},
+		files:     templateModTimes(cfg),
+	}
+
+	custom := map[string]string{
+		"dir":  expandPath(cfg.TemplateDirFile),
+		"body": expandPath(cfg.TemplateBodyFile),
+	}
+	base := template.New("").Funcs(templateFuncs(false, nil))
+	if dir := expandPath(cfg.TemplateDir); dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		var partials []string
+		for _, file := range matches {
+			name := strings.TrimSuffix(filepath.Base(file), ".html")
+			if _, ok := defaultTemplates[name]; !ok {
+				partials = append(partials, file)
+			} else if custom[name] == "" {
+				custom[name] = file
+			}
+		}
+		if len(partials) > 0 {
+			if _, err := base.ParseFiles(partials...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name, content := range defaultTemplates {
+		if file := custom[name]; file != "" {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			content = string(b)
+		}
+		set, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		t, err := set.New(name).Parse(content)
+		if err != nil {
+			if file := custom[name]; file != "" {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			return nil, err
+		}
+		ts.templates[name] = t
+	}
+	return ts, nil
+}
+
+// modified reports whether the template files are modified since loaded.
+func (ts *templateSet) modified(cfg *config) bool {
+	files := cfg.templateFiles()
+	if len(files) != len(ts.files) {
+		return true
+	}
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return true
+		}
+		if t, ok := ts.files[file]; !ok || !t.Equal(fi.ModTime()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ts *templateSet) execute(w io.Writer, name string, data interface{}, static bool, page *dirPage) error {
+	t, ok := ts.templates[name]
+	if !ok {
+		return fmt.Errorf("template %q is not defined", name)
+	}
+	t, err := t.Clone()
+	if err != nil {
+		return err
+	}
+	return t.Funcs(templateFuncs(static, page)).Execute(w, data)
+}
+
+// templates returns the templates loaded at the last time.
+func (srv *server) templates() (*templateSet, error) {
+	if ts := srv.tmpl.Load(); ts != nil {
+		return ts, nil
+	}
+	ts, err := srv.cfg.loadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	srv.tmpl.Store(ts)
+	return ts, nil
+}
+
+// watchTemplates reloads the templates when the files are modified. The
+// templates are replaced only if all of them are parsed successfully.
+func (srv *server) watchTemplates(interval time.Duration) {
+	for range time.Tick(interval) {
+		if ts := srv.tmpl.Load(); ts != nil && !ts.modified(srv.cfg) {
+			continue
+		}
+		ts, err := srv.cfg.loadTemplates()
+		if err != nil {
+			log.Println(err)
+			// keep the old templates, and do not try again until the files
+			// are modified
+			if old := srv.tmpl.Load(); old != nil {
+				srv.tmpl.Store(&templateSet{templates: old.templates, files: templateModTimes(srv.cfg)})
+			}
+			continue
+		}
+		srv.tmpl.Store(ts)
+		log.Println("templates reloaded")
+	}
+}
+
+// templateModTimes returns the modification times of the template files.
+func templateModTimes(cfg *config) map[string]time.Time {
+	files := map[string]time.Time{}
+	for _, file := range cfg.templateFiles() {
+		if fi, err := os.Stat(file); err == nil {
+			files[file] = fi.ModTime()
+		}
+	}
+	return files
+}
+
+// render writes the page rendered with the template. Errors while rendering
+// are shown as the error page.
+func (srv *server) render(w http.ResponseWriter, status int, name string, data interface{}, page *dirPage) {
+	ts, err := srv.templates()
+	var buf bytes.Buffer
+	if err == nil {
+		err = ts.execute(&buf, name, data, false, page)
+	}
+	if err != nil {
+		srv.renderError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// renderError writes the error page.
+func (srv *server) renderError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Println(err)
+	}
+	page := &errorPage{
+		Status:     status,
+		StatusText: http.StatusText(status),
+	}
+	if err != nil {
+		page.Message = err.Error()
+	}
+
+	var buf bytes.Buffer
+	ts, lerr := srv.templates()
+	if lerr == nil {
+		lerr = ts.execute(&buf, "error", page, false, nil)
+	}
+	if lerr != nil {
+		// the error template is broken. use the builtin one.
+		buf.Reset()
+		t, perr := template.New("error").Parse(templateErrorContent)
+		if perr != nil || t.Execute(&buf, page) != nil {
+			http.Error(w, page.Message, status)
+			return
+		}
+	}
+	w.Header().Set("content-type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/memo/index"
+	"github.com/mattn/memo/store"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"layout.html": `{{define "layout"}}<html><body>{{template "content" .}}</body></html>{{end}}`,
+		"dir.html":    `{{define "content"}}{{range .}}<a href="/{{.Name}}">{{truncate .Meta.Title 5}}</a>{{end}}{{end}}{{template "layout" .}}`,
+		"body.html":   `{{define "content"}}{{date .Meta.Date "2006/01/02"}} {{tagLinks .Meta.Tags}}{{end}}{{template "layout" .}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("---\ntitle: Foo Bar Baz\ndate: 2017-01-02\ntags: [a b]\n---\nfoo\n"))
+	srv := &server{cfg: &config{TemplateDir: dir}, s: s, idx: index.New()}
+	h := srv.handler()
+
+	if got, want := get(t, h, "/").Body.String(), `<html><body><a href="/2017-01-01-foo.md">Fo...</a></body></html>`; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+	if got, want := get(t, h, "/2017-01-01-foo.md").Body.String(), `<html><body>2017/01/02 <a href="/tags/a%20b">#a b</a></body></html>`; got != want {
+		t.Errorf("want %q but got %q", want, got)
+	}
+	// the builtin templates are used for the others
+	if got := get(t, h, "/search").Body.String(); !strings.Contains(got, `<form action="/search">`) {
+		t.Errorf("want builtin search page but got %q", got)
+	}
+}
+
+func TestTemplateReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dir.html")
+	if err := os.WriteFile(file, []byte(`old`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{TemplateDirFile: file}
+	ts, err := cfg.loadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.modified(cfg) {
+		t.Fatal("templates must not be modified")
+	}
+
+	if err := os.WriteFile(file, []byte(`{{broken`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(file, time.Now(), time.Now().Add(time.Second))
+	if !ts.modified(cfg) {
+		t.Fatal("templates must be modified")
+	}
+	if _, err := cfg.loadTemplates(); err == nil {
+		t.Fatal("want error for broken template")
+	}
+}
+
+func TestErrorPage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "body.html"), []byte(`{{.Unknown}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	srv := &server{cfg: &config{TemplateDir: dir}, s: s, idx: index.New()}
+	h := srv.handler()
+
+	w := get(t, h, "/2017-01-01-nothing.md")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "<h1>404 Not Found</h1>") {
+		t.Errorf("want styled 404 page but got %d %q", w.Code, w.Body.String())
+	}
+	w = get(t, h, "/2017-01-01-foo.md")
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Unknown") {
+		t.Errorf("want styled 500 page but got %d %q", w.Code, w.Body.String())
+	}
+}
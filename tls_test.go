@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	now := time.Now()
+	if err := generateCert(certFile, keyFile, []string{"localhost", "192.168.0.2"}, now); err != nil {
+		t.Fatal(err)
+	}
+	c, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Leaf.VerifyHostname("localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Leaf.VerifyHostname("192.168.0.2"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Leaf.NotAfter.After(now) {
+		t.Fatalf("certificate is expired: %v", c.Leaf.NotAfter)
+	}
+}
+
+func TestSelfSignedCert(t *testing.T) {
+	setupTestHome(t)
+	if err := os.MkdirAll(configDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile, err := selfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the certificate is reused
+	if _, _, err := selfSignedCert(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("certificate must not be generated again")
+	}
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+
+	"github.com/mattn/memo/store"
+)
+
+var trashCommand = &cli.Command{
+	Name:  "trash",
+	Usage: "manage deleted memo",
+	Subcommands: []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "list deleted memo",
+			Action: cmdTrashList,
+		},
+		{
+			Name:      "restore",
+			Usage:     "restore deleted memo",
+			ArgsUsage: "<name>",
+			Action:    cmdTrashRestore,
+		},
+		{
+			Name:   "empty",
+			Usage:  "delete memo in the trash permanently",
+			Action: cmdTrashEmpty,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "older-than",
+					Usage: "delete only memo deleted before the `duration` (e.g. 30d, 2w, 12h)",
+				},
+			},
+		},
+	},
+}
+
+// trashItem is the metadata of a memo in the trash.
+type trashItem struct {
+	ID        string    `json:"-"`
+	Name      string    `json:"name"`
+	MemoDir   string    `json:"memodir"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+func trashDir() string {
+	return filepath.Join(configDir(), "trash")
+}
+
+func (item *trashItem) path(ext string) string {
+	return filepath.Join(trashDir(), item.ID+ext)
+}
+
+// moveToTrash moves the memo into the trash.
+func (cfg *config) moveToTrash(s store.Store, name string) error {
+	memo, err := s.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(trashDir(), 0700); err != nil {
+		return err
+	}
+	now := time.Now()
+	item := &trashItem{
+		ID:        fmt.Sprintf("%d-%s", now.UnixNano(), filepath.Base(name)),
+		Name:      name,
+		MemoDir:   cfg.MemoDir,
+		DeletedAt: now,
+	}
+	b, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(item.path(".md"), memo.Body, 0600); err != nil {
+		return err
+	}
+	os.Chtimes(item.path(".md"), memo.ModTime, memo.ModTime)
+	if err := os.WriteFile(item.path(".json"), b, 0600); err != nil {
+		os.Remove(item.path(".md"))
+		return err
+	}
+	if err := s.Delete(name); err != nil {
+		item.remove()
+		return err
+	}
+	return nil
+}
+
+func (item *trashItem) remove() error {
+	err := os.Remove(item.path(".md"))
+	if err1 := os.Remove(item.path(".json")); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// trashItems returns the memos in the trash, latest first.
+func trashItems() ([]*trashItem, error) {
+	files, err := filepath.Glob(filepath.Join(trashDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var items []*trashItem
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var item trashItem
+		if err := json.Unmarshal(b, &item); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		item.ID = strings.TrimSuffix(filepath.Base(file), ".json")
+		items = append(items, &item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// parseAge parses the duration which can have "d" (days) or "w" (weeks)
+// units in addition to time.ParseDuration.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %q", s)
+	}
+	return d, nil
+}
+
+func cmdTrashList(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	items, err := trashItems()
+	if err != nil {
+		return err
+	}
+	istty := isatty.IsTerminal(os.Stdout.Fd())
+	for _, item := range items {
+		deletedAt := item.DeletedAt.Format("2006-01-02 15:04")
+		if istty {
+			fmt.Fprintf(color.Output, "%s : %s (%s)\n", color.YellowString(deletedAt), color.GreenString(item.Name), item.MemoDir)
+		} else {
+			fmt.Printf("%s\t%s\t%s\n", deletedAt, item.Name, item.MemoDir)
+		}
+	}
+	return nil
+}
+
+func cmdTrashRestore(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	if !c.Args().Present() {
+		return errors.New("name required")
+	}
+	name := c.Args().First()
+	items, err := trashItems()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.Name != name && item.ID != name {
+			continue
+		}
+		b, err := os.ReadFile(item.path(".md"))
+		if err != nil {
+			return err
+		}
+		if err := newStore(item.MemoDir).Create(item.Name, b); err != nil {
+			return err
+		}
+		restored := &config{MemoDir: item.MemoDir, NoAutoCommit: cfg.NoAutoCommit}
+		if err := restored.autoCommit("restore: "+item.Name, item.Name); err != nil {
+			return err
+		}
+		color.Yellow("Restored: %v", filepath.Join(item.MemoDir, item.Name))
+		return item.remove()
+	}
+	return fmt.Errorf("%s is not in the trash", name)
+}
+
+func cmdTrashEmpty(c *cli.Context) error {
+	var cfg config
+	err := cfg.load()
+	if err != nil {
+		return err
+	}
+
+	items, err := trashItems()
+	if err != nil {
+		return err
+	}
+	if c.IsSet("older-than") {
+		age, err := parseAge(c.String("older-than"))
+		if err != nil {
+			return err
+		}
+		limit := time.Now().Add(-age)
+		var olds []*trashItem
+		for _, item := range items {
+			if item.DeletedAt.Before(limit) {
+				olds = append(olds, item)
+			}
+		}
+		items = olds
+	} else if len(items) > 0 {
+		color.Red("Will delete %d entry in the trash permanently.", len(items))
+		answer, err := ask("Are you sure? (y/N)")
+		if answer == false || err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		if err := item.remove(); err != nil {
+			return err
+		}
+		color.Yellow("Deleted: %v", item.Name)
+	}
+	return nil
+}
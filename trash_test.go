@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/memo/store"
+)
+
+func TestTrash(t *testing.T) {
+	setupTestHome(t)
+	s := store.NewMemory()
+	s.Create("2017-01-01-foo.md", []byte("# Foo\n"))
+	s.Create("2017-01-02-bar.md", []byte("# Bar\n"))
+
+	cfg := &config{MemoDir: "memodir"}
+	if err := cfg.moveToTrash(s, "2017-01-01-foo.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.moveToTrash(s, "2017-01-02-bar.md"); err != nil {
+		t.Fatal(err)
+	}
+	if memos, _ := s.List(); len(memos) != 0 {
+		t.Fatalf("want no memos but got %d", len(memos))
+	}
+
+	out := runTestApp(t, s, "trash", "list")
+	if !strings.Contains(out, "\t2017-01-01-foo.md\tmemodir\n") || !strings.Contains(out, "\t2017-01-02-bar.md\tmemodir\n") {
+		t.Fatalf("unexpected trash list: %q", out)
+	}
+
+	runTestApp(t, s, "trash", "restore", "2017-01-01-foo.md")
+	memo, err := s.Get("2017-01-01-foo.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(memo.Body) != "# Foo\n" {
+		t.Fatalf("unexpected body: %q", string(memo.Body))
+	}
+
+	runTestApp(t, s, "trash", "empty", "--older-than", "1d")
+	if items, _ := trashItems(); len(items) != 1 {
+		t.Fatalf("want 1 item in the trash but got %d", len(items))
+	}
+	runTestApp(t, s, "trash", "empty", "--older-than", "0s")
+	if items, _ := trashItems(); len(items) != 0 {
+		t.Fatalf("want empty trash but got %d", len(items))
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for s, expect := range map[string]time.Duration{
+		"30d": 30 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+	} {
+		got, err := parseAge(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expect {
+			t.Errorf("%s: want %v but got %v", s, expect, got)
+		}
+	}
+	if _, err := parseAge("soon"); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,131 @@
+// Package wiki handles [[wiki links]] between memos.
+package wiki
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var linkReg = regexp.MustCompile(`\[\[([^\[\]\n|]+)(?:\|([^\[\]\n]+))?\]\]`)
+
+// Replace replaces the wiki links in content with the result of fn. target is
+// the memo name or the title, and label is the text after "|", or target if
+// not given. If fn returns false, the link is not replaced. Links in code
+// blocks and code spans are ignored.
+func Replace(content []byte, fn func(target, label string) (string, bool)) []byte {
+	var buf bytes.Buffer
+	fence := ""
+	for _, line := range bytes.SplitAfter(content, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			buf.Write(line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			buf.Write(line)
+			continue
+		}
+		// odd fields are code spans
+		for i, field := range bytes.Split(line, []byte("`")) {
+			if i > 0 {
+				buf.WriteByte('`')
+			}
+			if i%2 == 1 {
+				buf.Write(field)
+				continue
+			}
+			buf.Write(linkReg.ReplaceAllFunc(field, func(m []byte) []byte {
+				sm := linkReg.FindSubmatch(m)
+				target := strings.TrimSpace(string(sm[1]))
+				label := strings.TrimSpace(string(sm[2]))
+				if label == "" {
+					label = target
+				}
+				if s, ok := fn(target, label); ok {
+					return []byte(s)
+				}
+				return m
+			}))
+		}
+	}
+	return buf.Bytes()
+}
+
+// Links returns the targets of the wiki links in content.
+func Links(content []byte) []string {
+	var links []string
+	Replace(content, func(target, label string) (string, bool) {
+		links = append(links, target)
+		return "", false
+	})
+	return links
+}
+
+// Page is the memo which can be linked.
+type Page struct {
+	Name  string
+	Title string
+}
+
+// Resolver resolves the targets of links to the memo names.
+type Resolver struct {
+	names  map[string]string
+	titles map[string]string
+	slugs  map[string]string
+}
+
+var datePrefixReg = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}-`)
+
+// slug returns the name of the memo without the notebook, the date prefix
+// and the extension.
+func slug(name string) string {
+	return datePrefixReg.ReplaceAllString(strings.TrimSuffix(path.Base(name), ".md"), "")
+}
+
+// NewResolver returns the resolver for pages. When some pages have the same
+// title or slug, the first one is used.
+func NewResolver(pages []Page) *Resolver {
+	r := &Resolver{
+		names:  map[string]string{},
+		titles: map[string]string{},
+		slugs:  map[string]string{},
+	}
+	add := func(m map[string]string, key, name string) {
+		key = strings.ToLower(key)
+		if _, ok := m[key]; !ok && key != "" {
+			m[key] = name
+		}
+	}
+	for _, p := range pages {
+		add(r.names, p.Name, p.Name)
+		add(r.names, strings.TrimSuffix(p.Name, ".md"), p.Name)
+		add(r.names, path.Base(p.Name), p.Name)
+		add(r.names, strings.TrimSuffix(path.Base(p.Name), ".md"), p.Name)
+		add(r.titles, strings.TrimSpace(p.Title), p.Name)
+		add(r.slugs, slug(p.Name), p.Name)
+	}
+	return r
+}
+
+// Resolve returns the name of the memo linked with target. target is
+// compared with the memo name, the title, and the memo name without the date
+// prefix, ignoring case.
+func (r *Resolver) Resolve(target string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(target))
+	for _, m := range []map[string]string{r.names, r.titles, r.slugs} {
+		if name, ok := m[key]; ok {
+			return name, true
+		}
+	}
+	// [[Memo Name]] for 2017-01-01-memo-name.md
+	if name, ok := r.slugs[strings.Join(strings.Fields(key), "-")]; ok {
+		return name, true
+	}
+	return "", false
+}
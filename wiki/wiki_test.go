@@ -0,0 +1,61 @@
+package wiki
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	content := []byte("see [[foo]] and [[Bar Title|bar]].\n" +
+		"`[[code]]` is not a link, [[baz]] is.\n" +
+		"```\n[[fenced]]\n```\n" +
+		"[[ spaced ]] [not a link] [[]]\n")
+	got := Links(content)
+	want := []string{"foo", "Bar Title", "baz", "spaced"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	content := []byte("[[foo]] [[bar|Bar]] `[[foo]]`\n")
+	got := Replace(content, func(target, label string) (string, bool) {
+		if target == "foo" {
+			return "<" + label + ">", true
+		}
+		return "", false
+	})
+	if want := "<foo> [[bar|Bar]] `[[foo]]`\n"; string(got) != want {
+		t.Fatalf("want %q but got %q", want, got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	r := NewResolver([]Page{
+		{Name: "2017-01-02-memo-name.md", Title: "Memo Name"},
+		{Name: "work/2017-01-01-meeting.md", Title: "Weekly Meeting"},
+		{Name: "2016-01-01-memo-name.md", Title: "Old"},
+		{Name: "readme.md", Title: "Read Me"},
+	})
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{"2017-01-02-memo-name.md", "2017-01-02-memo-name.md", true},
+		{"2016-01-01-memo-name", "2016-01-01-memo-name.md", true},
+		{"memo-name", "2017-01-02-memo-name.md", true},
+		{"weekly meeting", "work/2017-01-01-meeting.md", true},
+		{"work/2017-01-01-meeting", "work/2017-01-01-meeting.md", true},
+		{"meeting", "work/2017-01-01-meeting.md", true},
+		{"readme", "readme.md", true},
+		{"Old", "2016-01-01-memo-name.md", true},
+		{"nothing", "", false},
+	}
+	for _, test := range tests {
+		got, ok := r.Resolve(test.target)
+		if got != test.want || ok != test.ok {
+			t.Errorf("Resolve(%q): want %q, %v but got %q, %v", test.target, test.want, test.ok, got, ok)
+		}
+	}
+}